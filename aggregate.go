@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GLobyNew/gator/internal/database"
@@ -23,6 +26,12 @@ func handlerAgg(s *state, cmd command) error {
 	ticker := time.NewTicker(timeBetweenRequests)
 	for ; ; <-ticker.C {
 		scrapeFeeds(s)
+		if err := deliverWebhooks(s, time.Now()); err != nil {
+			fmt.Printf("Couldn't deliver webhooks: %v\n", err)
+		}
+		if err := sendDueDigests(s, time.Now()); err != nil {
+			fmt.Printf("Couldn't send digests: %v\n", err)
+		}
 	}
 
 }
@@ -43,30 +52,179 @@ func scrapeFeeds(s *state) error {
 		return err
 	}
 
+	err = s.db.SetFeedParseWarnings(context.Background(), database.SetFeedParseWarningsParams{
+		ID:            feedToFetch.ID,
+		ParseWarnings: sql.NullString{String: strings.Join(fetchedFeed.Warnings, "; "), Valid: len(fetchedFeed.Warnings) > 0},
+	})
+	if err != nil {
+		return err
+	}
+
+	if fetchedFeed.Channel.Link != "" {
+		err = s.db.SetFeedSiteURL(context.Background(), database.SetFeedSiteURLParams{
+			ID:      feedToFetch.ID,
+			SiteUrl: strings.TrimSpace(fetchedFeed.Channel.Link),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = ingestFeed(s, feedToFetch, fetchedFeed)
+	return err
+}
+
+// ingestFeed stores the items of fetchedFeed as posts of feed, skipping the ones already stored.
+// It returns how many posts were stored.
+func ingestFeed(s *state, feed database.Feed, fetchedFeed *RSSFeed) (int, error) {
+	rules, err := s.db.GetFilterRulesForFeed(context.Background(), feed.ID)
+	if err != nil {
+		return 0, err
+	}
+	filterRules := compileRules(rules)
+
+	webhooks, err := s.db.GetWebhooksForFeed(context.Background(), feed.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
 	for _, item := range fetchedFeed.Channel.Item {
 		pubTime, err := time.Parse(time.RFC1123Z, item.PubDate)
 		if err != nil {
-			return err
+			return stored, err
+		}
+
+		// The article is downloaded before the transaction is opened, so a slow page
+		// doesn't hold it open
+		var content string
+		if feed.FetchFullContent {
+			_, err := s.db.GetPostByURL(context.Background(), item.Link)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return stored, err
+			}
+			content = fetchFullContent(item.Link)
 		}
 
-		_, err = s.db.CreatePost(context.Background(), database.CreatePostParams{
-			ID: uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			PublishedAt: pubTime,
-			Title: item.Title,
-			Url: item.Link,
-			Description: item.Description,
-			FeedID: feedToFetch.ID,
+		// A post is stored, filtered, queued for webhooks and announced in one transaction,
+		// so watchers are notified on commit and a failure leaves nothing half done.
+		err = inTx(s, nil, func(tx *state) error {
+			return ingestItem(tx, feed, item, pubTime, content, filterRules, webhooks)
 		})
 		if err != nil {
 			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // 23505 is the PostgreSQL error code for unique violations
 				continue
 			} else {
-				return err
+				return stored, err
 			} 
 		}
+		stored++
+	}
+
+	return stored, nil
+}
+
+// ingestItem stores item as a post of feed, with content as its full article if it isn't
+// empty, and runs everything that follows from a new post.
+func ingestItem(s *state, feed database.Feed, item RSSItem, pubTime time.Time, content string, filterRules []compiledRule, webhooks []database.Webhook) error {
+	post, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		PublishedAt: pubTime,
+		Title:       item.Title,
+		Url:         item.Link,
+		Description: item.Description,
+		FeedID:      feed.ID,
+		Author:      itemAuthor(item),
+		Categories:  itemCategories(item),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, enclosure := range item.Enclosure {
+		length, _ := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+		err = s.db.CreatePostEnclosure(context.Background(), database.CreatePostEnclosureParams{
+			ID:       uuid.New(),
+			PostID:   post.ID,
+			Url:      enclosure.URL,
+			MimeType: enclosure.Type,
+			Length:   length,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if content != "" {
+		err = storeFullContent(s, post.ID, content)
+		if err != nil {
+			return fmt.Errorf("couldn't store full content of %q: %w", post.Url, err)
+		}
+	}
+
+	fp := filteredPost{
+		ID:          post.ID,
+		FeedID:      post.FeedID,
+		Title:       post.Title,
+		Description: post.Description,
+		Content:     content,
+		Author:      post.Author,
+		Categories:  post.Categories,
+	}
+	_, err = applyFilterRules(s, filterRules, fp)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	err = enqueueWebhooks(s, webhooks, filterRules, feed, post, fp)
+	if err != nil {
+		return err
+	}
+
+	return s.db.NotifyNewPost(context.Background(), database.NotifyNewPostParams{
+		Channel: newPostsChannel,
+		FeedID:  feed.ID,
+		PostID:  post.ID,
+	})
+}
+
+// fetchFullContent downloads the article behind pageURL. Extraction failures are reported
+// but don't stop the rest of the feed from being ingested, the post is stored without it.
+func fetchFullContent(pageURL string) string {
+	content, err := extractArticle(context.Background(), pageURL)
+	if err != nil {
+		fmt.Printf("couldn't extract full content of %q: %v\n", pageURL, err)
+		return ""
+	}
+	return content
+}
+
+// storeFullContent stores the article extracted from a post's page next to its description.
+func storeFullContent(s *state, postID uuid.UUID, content string) error {
+	return s.db.UpdatePostContent(context.Background(), database.UpdatePostContentParams{
+		ID:      postID,
+		Content: sql.NullString{String: content, Valid: true},
+	})
+}
+
+func itemAuthor(item RSSItem) string {
+	if item.Author != "" {
+		return strings.TrimSpace(item.Author)
+	}
+	return strings.TrimSpace(item.Creator)
+}
+
+func itemCategories(item RSSItem) []string {
+	categories := []string{}
+	for _, category := range item.Category {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories
 }
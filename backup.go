@@ -0,0 +1,1069 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Backups are gzipped JSON objects. The version is bumped whenever the layout of the
+// sections changes; restore refuses versions it doesn't know.
+const (
+	backupFormat  = "gator-backup"
+	backupVersion = 2
+)
+
+type backupUser struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Name           string     `json:"name"`
+	LastDigestAt   *time.Time `json:"last_digest_at,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	DigestSchedule string     `json:"digest_schedule,omitempty"`
+	LastMailedAt   *time.Time `json:"last_mailed_at,omitempty"`
+}
+
+type backupFeed struct {
+	ID               uuid.UUID `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	Name             string    `json:"name"`
+	URL              string    `json:"url"`
+	UserID           uuid.UUID `json:"user_id"`
+	FetchFullContent bool      `json:"fetch_full_content"`
+	ParseWarnings    string    `json:"parse_warnings,omitempty"`
+	Language         string    `json:"language"`
+	SiteURL          string    `json:"site_url,omitempty"`
+}
+
+type backupFollow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Tags      []string  `json:"tags"`
+}
+
+type backupPost struct {
+	ID          uuid.UUID           `json:"id"`
+	CreatedAt   time.Time           `json:"created_at"`
+	PublishedAt time.Time           `json:"published_at"`
+	Title       string              `json:"title"`
+	URL         string              `json:"url"`
+	Description string              `json:"description"`
+	FeedID      uuid.UUID           `json:"feed_id"`
+	Content     *string             `json:"content,omitempty"`
+	Author      string              `json:"author"`
+	Categories  []string            `json:"categories"`
+	Enclosures  []exportedEnclosure `json:"enclosures"`
+}
+
+// backupPostState is a read, star or hide of a post by a user.
+type backupPostState struct {
+	UserID uuid.UUID `json:"user_id"`
+	PostID uuid.UUID `json:"post_id"`
+	At     time.Time `json:"at"`
+}
+
+type backupPostTag struct {
+	UserID uuid.UUID `json:"user_id"`
+	PostID uuid.UUID `json:"post_id"`
+	Tag    string    `json:"tag"`
+}
+
+type backupFilterRule struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	FeedID    *uuid.UUID `json:"feed_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Field     string     `json:"field"`
+	MatchType string     `json:"match_type"`
+	Pattern   string     `json:"pattern"`
+	Action    string     `json:"action"`
+	Tag       string     `json:"tag,omitempty"`
+}
+
+type backupSavedSearch struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	Language  string    `json:"language,omitempty"`
+}
+
+// backupWebhook leaves out the deliveries still queued for the webhook; only new posts
+// are delivered after a restore.
+type backupWebhook struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	URL       string     `json:"url"`
+	Secret    string     `json:"secret"`
+	FeedID    *uuid.UUID `json:"feed_id,omitempty"`
+	Tag       string     `json:"tag,omitempty"`
+	FilterID  *uuid.UUID `json:"filter_id,omitempty"`
+}
+
+// archiveWriter streams a backup as one JSON object whose sections are arrays.
+type archiveWriter struct {
+	w     io.Writer
+	items int
+}
+
+func (a *archiveWriter) begin() error {
+	_, err := fmt.Fprintf(a.w, "{\"format\":%q,\"version\":%d,\"created_at\":%q", backupFormat, backupVersion, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func (a *archiveWriter) startSection(name string) error {
+	a.items = 0
+	_, err := fmt.Fprintf(a.w, ",\n%q:[", name)
+	return err
+}
+
+func (a *archiveWriter) item(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	separator := "\n"
+	if a.items > 0 {
+		separator = ",\n"
+	}
+	a.items++
+	_, err = fmt.Fprintf(a.w, "%s%s", separator, data)
+	return err
+}
+
+func (a *archiveWriter) endSection() error {
+	_, err := io.WriteString(a.w, "]")
+	return err
+}
+
+func (a *archiveWriter) end() error {
+	_, err := io.WriteString(a.w, "}\n")
+	return err
+}
+
+// writeSection writes items as the section name of the archive.
+func writeSection[T any](a *archiveWriter, name string, items []T) error {
+	if err := a.startSection(name); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := a.item(item); err != nil {
+			return err
+		}
+	}
+	return a.endSection()
+}
+
+func handlerBackup(s *state, cmd command) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'backup' expects only one argument: <file>")
+	}
+
+	f, err := os.Create(cmd.args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Reading every table in one repeatable read transaction makes the backup a consistent
+	// snapshot even while agg keeps storing posts.
+	err = inTx(s, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, func(tx *state) error {
+		zw := gzip.NewWriter(f)
+		if err := writeBackup(tx, &archiveWriter{w: zw}); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		return f.Close()
+	})
+	if err != nil {
+		f.Close()
+		os.Remove(cmd.args[0])
+		return err
+	}
+
+	fmt.Printf("Backed up the database to %s\n", cmd.args[0])
+	return nil
+}
+
+func writeBackup(s *state, a *archiveWriter) error {
+	ctx := context.Background()
+	if err := a.begin(); err != nil {
+		return err
+	}
+
+	users, err := s.db.BackupUsers(ctx)
+	if err != nil {
+		return err
+	}
+	backupUsers := make([]backupUser, 0, len(users))
+	for _, user := range users {
+		item := backupUser{
+			ID:             user.ID,
+			CreatedAt:      user.CreatedAt,
+			Name:           user.Name,
+			Email:          user.Email,
+			DigestSchedule: user.DigestSchedule,
+		}
+		if user.LastDigestAt.Valid {
+			item.LastDigestAt = &user.LastDigestAt.Time
+		}
+		if user.LastMailedAt.Valid {
+			item.LastMailedAt = &user.LastMailedAt.Time
+		}
+		backupUsers = append(backupUsers, item)
+	}
+	if err := writeSection(a, "users", backupUsers); err != nil {
+		return err
+	}
+
+	feeds, err := s.db.BackupFeeds(ctx)
+	if err != nil {
+		return err
+	}
+	backupFeeds := make([]backupFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		backupFeeds = append(backupFeeds, backupFeed{
+			ID:               feed.ID,
+			CreatedAt:        feed.CreatedAt,
+			Name:             feed.Name,
+			URL:              feed.Url,
+			UserID:           feed.UserID,
+			FetchFullContent: feed.FetchFullContent,
+			ParseWarnings:    feed.ParseWarnings.String,
+			Language:         feed.Language,
+			SiteURL:          feed.SiteUrl,
+		})
+	}
+	if err := writeSection(a, "feeds", backupFeeds); err != nil {
+		return err
+	}
+
+	follows, err := s.db.BackupFeedFollows(ctx)
+	if err != nil {
+		return err
+	}
+	backupFollows := make([]backupFollow, 0, len(follows))
+	for _, follow := range follows {
+		backupFollows = append(backupFollows, backupFollow{
+			UserID:    follow.UserID,
+			FeedID:    follow.FeedID,
+			CreatedAt: follow.CreatedAt,
+			Tags:      follow.Tags,
+		})
+	}
+	if err := writeSection(a, "feed_follows", backupFollows); err != nil {
+		return err
+	}
+
+	// Posts are the bulk of a backup, so they are read and written in batches
+	if err := a.startSection("posts"); err != nil {
+		return err
+	}
+	afterID := uuid.Nil
+	for {
+		posts, err := s.db.BackupPosts(ctx, database.BackupPostsParams{AfterID: afterID, PostLimit: exportBatchSize})
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			enclosures, err := s.db.GetPostEnclosures(ctx, post.ID)
+			if err != nil {
+				return err
+			}
+			item := backupPost{
+				ID:          post.ID,
+				CreatedAt:   post.CreatedAt,
+				PublishedAt: post.PublishedAt,
+				Title:       post.Title,
+				URL:         post.Url,
+				Description: post.Description,
+				FeedID:      post.FeedID,
+				Author:      post.Author,
+				Categories:  post.Categories,
+				Enclosures:  []exportedEnclosure{},
+			}
+			if post.Content.Valid {
+				item.Content = &post.Content.String
+			}
+			for _, enclosure := range enclosures {
+				item.Enclosures = append(item.Enclosures, exportedEnclosure{
+					URL:      enclosure.Url,
+					MimeType: enclosure.MimeType,
+					Length:   enclosure.Length,
+				})
+			}
+			if err := a.item(item); err != nil {
+				return err
+			}
+		}
+		if len(posts) < exportBatchSize {
+			break
+		}
+		afterID = posts[len(posts)-1].ID
+	}
+	if err := a.endSection(); err != nil {
+		return err
+	}
+
+	reads, err := s.db.BackupPostReads(ctx)
+	if err != nil {
+		return err
+	}
+	backupReads := make([]backupPostState, 0, len(reads))
+	for _, read := range reads {
+		backupReads = append(backupReads, backupPostState{UserID: read.UserID, PostID: read.PostID, At: read.ReadAt})
+	}
+	if err := writeSection(a, "post_reads", backupReads); err != nil {
+		return err
+	}
+
+	stars, err := s.db.BackupPostStars(ctx)
+	if err != nil {
+		return err
+	}
+	backupStars := make([]backupPostState, 0, len(stars))
+	for _, star := range stars {
+		backupStars = append(backupStars, backupPostState{UserID: star.UserID, PostID: star.PostID, At: star.StarredAt})
+	}
+	if err := writeSection(a, "post_stars", backupStars); err != nil {
+		return err
+	}
+
+	hides, err := s.db.BackupPostHides(ctx)
+	if err != nil {
+		return err
+	}
+	backupHides := make([]backupPostState, 0, len(hides))
+	for _, hide := range hides {
+		backupHides = append(backupHides, backupPostState{UserID: hide.UserID, PostID: hide.PostID, At: hide.HiddenAt})
+	}
+	if err := writeSection(a, "post_hides", backupHides); err != nil {
+		return err
+	}
+
+	tags, err := s.db.BackupPostTags(ctx)
+	if err != nil {
+		return err
+	}
+	backupTags := make([]backupPostTag, 0, len(tags))
+	for _, tag := range tags {
+		backupTags = append(backupTags, backupPostTag{UserID: tag.UserID, PostID: tag.PostID, Tag: tag.Tag})
+	}
+	if err := writeSection(a, "post_tags", backupTags); err != nil {
+		return err
+	}
+
+	rules, err := s.db.BackupFilterRules(ctx)
+	if err != nil {
+		return err
+	}
+	backupRules := make([]backupFilterRule, 0, len(rules))
+	for _, rule := range rules {
+		item := backupFilterRule{
+			ID:        rule.ID,
+			UserID:    rule.UserID,
+			CreatedAt: rule.CreatedAt,
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Action:    rule.Action,
+			Tag:       rule.Tag,
+		}
+		if rule.FeedID.Valid {
+			item.FeedID = &rule.FeedID.UUID
+		}
+		backupRules = append(backupRules, item)
+	}
+	if err := writeSection(a, "filter_rules", backupRules); err != nil {
+		return err
+	}
+
+	searches, err := s.db.BackupSavedSearches(ctx)
+	if err != nil {
+		return err
+	}
+	backupSearches := make([]backupSavedSearch, 0, len(searches))
+	for _, search := range searches {
+		backupSearches = append(backupSearches, backupSavedSearch{
+			UserID:    search.UserID,
+			CreatedAt: search.CreatedAt,
+			Name:      search.Name,
+			Query:     search.Query,
+			Language:  search.Language.String,
+		})
+	}
+	if err := writeSection(a, "saved_searches", backupSearches); err != nil {
+		return err
+	}
+
+	hooks, err := s.db.BackupWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+	backupHooks := make([]backupWebhook, 0, len(hooks))
+	for _, hook := range hooks {
+		item := backupWebhook{
+			UserID:    hook.UserID,
+			CreatedAt: hook.CreatedAt,
+			URL:       hook.Url,
+			Secret:    hook.Secret,
+			Tag:       hook.Tag,
+		}
+		if hook.FeedID.Valid {
+			item.FeedID = &hook.FeedID.UUID
+		}
+		if hook.FilterID.Valid {
+			item.FilterID = &hook.FilterID.UUID
+		}
+		backupHooks = append(backupHooks, item)
+	}
+	if err := writeSection(a, "webhooks", backupHooks); err != nil {
+		return err
+	}
+
+	return a.end()
+}
+
+// restorer merges a backup into the database. Users, feeds and posts that already exist
+// (by name or URL) are reused; new rows keep their backed up ID unless it is taken. The
+// IDs of the backup are mapped to the ones used in the database for the later sections.
+type restorer struct {
+	s          *state
+	users      map[uuid.UUID]uuid.UUID
+	feeds      map[uuid.UUID]uuid.UUID
+	posts      map[uuid.UUID]uuid.UUID
+	rules      map[uuid.UUID]uuid.UUID
+	follows    map[uuid.UUID]map[uuid.UUID]bool
+	feedNames  map[string]bool
+	added      map[string]int
+	merged     map[string]int
+	hasVersion bool
+}
+
+func handlerRestore(s *state, cmd command) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'restore' expects only one argument: <file>")
+	}
+
+	f, err := os.Open(cmd.args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s isn't a gator backup: %w", cmd.args[0], err)
+	}
+	defer zr.Close()
+
+	r, err := restoreBackup(s, zr)
+	if err != nil {
+		return err
+	}
+
+	for _, section := range []string{"users", "feeds", "feed_follows", "posts", "post_reads", "post_stars", "post_hides", "post_tags", "filter_rules", "saved_searches", "webhooks"} {
+		fmt.Printf("%-15s: %d added, %d already present\n", section, r.added[section], r.merged[section])
+	}
+	return nil
+}
+
+// restoreBackup merges the uncompressed backup read from rd into the database in one
+// transaction, so a backup is restored completely or not at all.
+func restoreBackup(s *state, rd io.Reader) (*restorer, error) {
+	r := &restorer{
+		users:     make(map[uuid.UUID]uuid.UUID),
+		feeds:     make(map[uuid.UUID]uuid.UUID),
+		posts:     make(map[uuid.UUID]uuid.UUID),
+		rules:     make(map[uuid.UUID]uuid.UUID),
+		follows:   make(map[uuid.UUID]map[uuid.UUID]bool),
+		feedNames: make(map[string]bool),
+		added:     make(map[string]int),
+		merged:    make(map[string]int),
+	}
+	err := inTx(s, nil, func(tx *state) error {
+		r.s = tx
+		return r.restore(json.NewDecoder(rd))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *restorer) restore(dec *json.Decoder) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	var format string
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+
+		switch key {
+		case "format":
+			if err := dec.Decode(&format); err != nil {
+				return err
+			}
+			if format != backupFormat {
+				return fmt.Errorf("not a gator backup (format %q)", format)
+			}
+			continue
+		case "version":
+			var version int
+			if err := dec.Decode(&version); err != nil {
+				return err
+			}
+			if version < 1 || version > backupVersion {
+				return fmt.Errorf("unsupported backup version %d, this gator reads up to version %d", version, backupVersion)
+			}
+			r.hasVersion = true
+			continue
+		}
+
+		if format != backupFormat || !r.hasVersion {
+			return errors.New("not a gator backup: missing format or version")
+		}
+
+		switch key {
+		case "users":
+			err = restoreSection(dec, r.restoreUser)
+		case "feeds":
+			err = restoreSection(dec, r.restoreFeed)
+		case "feed_follows":
+			err = restoreSection(dec, r.restoreFollow)
+		case "posts":
+			err = restoreSection(dec, r.restorePost)
+		case "post_reads":
+			err = restoreSection(dec, func(state backupPostState) error {
+				return r.restorePostState("post_reads", state)
+			})
+		case "post_stars":
+			err = restoreSection(dec, func(state backupPostState) error {
+				return r.restorePostState("post_stars", state)
+			})
+		case "post_hides":
+			err = restoreSection(dec, func(state backupPostState) error {
+				return r.restorePostState("post_hides", state)
+			})
+		case "post_tags":
+			err = restoreSection(dec, r.restorePostTag)
+		case "filter_rules":
+			err = restoreSection(dec, r.restoreFilterRule)
+		case "saved_searches":
+			err = restoreSection(dec, r.restoreSavedSearch)
+		case "webhooks":
+			err = restoreSection(dec, r.restoreWebhook)
+		default:
+			// Fields this version doesn't know about are skipped
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return fmt.Errorf("restoring %s: %w", key, err)
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// restoreSection decodes the items of a section one at a time and hands them to restore.
+func restoreSection[T any](dec *json.Decoder, restore func(T) error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		if err := restore(item); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("malformed backup: expected %q, got %v", delim, token)
+	}
+	return nil
+}
+
+func (r *restorer) restoreUser(item backupUser) error {
+	ctx := context.Background()
+	user, err := r.s.db.GetUser(ctx, item.Name)
+	if err == nil {
+		r.users[item.ID] = user.ID
+		r.merged["users"]++
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	id, err := freeID(item.ID, func(id uuid.UUID) error {
+		_, err := r.s.db.GetUserByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	user, err = r.s.db.CreateUser(ctx, database.CreateUserParams{
+		ID:        id,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: time.Now(),
+		Name:      item.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if item.LastDigestAt != nil {
+		err = r.s.db.SetUserLastDigest(ctx, database.SetUserLastDigestParams{
+			ID:           user.ID,
+			LastDigestAt: sql.NullTime{Time: *item.LastDigestAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if item.LastMailedAt != nil {
+		err = r.s.db.SetUserLastMailed(ctx, database.SetUserLastMailedParams{
+			ID:           user.ID,
+			LastMailedAt: sql.NullTime{Time: *item.LastMailedAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if item.Email != "" {
+		err = r.s.db.SetUserDigestEmail(ctx, database.SetUserDigestEmailParams{
+			ID:             user.ID,
+			Email:          item.Email,
+			DigestSchedule: item.DigestSchedule,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	r.users[item.ID] = user.ID
+	r.added["users"]++
+	return nil
+}
+
+func (r *restorer) restoreFeed(item backupFeed) error {
+	ctx := context.Background()
+	feed, err := r.s.db.GetFeedByURL(ctx, item.URL)
+	if err == nil {
+		r.feeds[item.ID] = feed.ID
+		r.merged["feeds"]++
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	userID, ok := r.users[item.UserID]
+	if !ok {
+		return fmt.Errorf("feed %q belongs to a user missing from the backup", item.Name)
+	}
+	id, err := freeID(item.ID, func(id uuid.UUID) error {
+		_, err := r.s.db.GetFeedByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	name, err := uniqueFeedName(r.s, item.Name, r.feedNames)
+	if err != nil {
+		return err
+	}
+	r.feedNames[name] = true
+
+	feed, err = r.s.db.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        id,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: time.Now(),
+		Name:      name,
+		Url:       item.URL,
+		UserID:    userID,
+	})
+	if err != nil {
+		return err
+	}
+	err = r.s.db.SetFeedFetchFullContent(ctx, database.SetFeedFetchFullContentParams{
+		ID:               feed.ID,
+		FetchFullContent: item.FetchFullContent,
+	})
+	if err != nil {
+		return err
+	}
+	if item.Language != "" {
+		err = r.s.db.SetFeedLanguage(ctx, database.SetFeedLanguageParams{
+			Language: item.Language,
+			ID:       feed.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = r.s.db.SetFeedParseWarnings(ctx, database.SetFeedParseWarningsParams{
+		ID:            feed.ID,
+		ParseWarnings: sql.NullString{String: item.ParseWarnings, Valid: item.ParseWarnings != ""},
+	})
+	if err != nil {
+		return err
+	}
+	err = r.s.db.SetFeedSiteURL(ctx, database.SetFeedSiteURLParams{
+		ID:      feed.ID,
+		SiteUrl: item.SiteURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.feeds[item.ID] = feed.ID
+	r.added["feeds"]++
+	return nil
+}
+
+func (r *restorer) restoreFollow(item backupFollow) error {
+	ctx := context.Background()
+	userID, feedID, ok := r.userAndFeed(item.UserID, item.FeedID)
+	if !ok {
+		return errors.New("feed follow refers to a user or feed missing from the backup")
+	}
+
+	if _, ok := r.follows[userID]; !ok {
+		follows, err := r.s.db.GetFeedFollowsForUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		r.follows[userID] = make(map[uuid.UUID]bool, len(follows))
+		for _, follow := range follows {
+			r.follows[userID][follow.FeedID] = true
+		}
+	}
+
+	if r.follows[userID][feedID] {
+		r.merged["feed_follows"]++
+	} else {
+		_, err := r.s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: item.CreatedAt,
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			FeedID:    feedID,
+		})
+		if err != nil {
+			return err
+		}
+		r.follows[userID][feedID] = true
+		r.added["feed_follows"]++
+	}
+
+	for _, tag := range item.Tags {
+		_, err := r.s.db.TagFeedFollow(ctx, database.TagFeedFollowParams{
+			Tag:       tag,
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			FeedID:    feedID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restorePost(item backupPost) error {
+	ctx := context.Background()
+	post, err := r.s.db.GetPostByURL(ctx, item.URL)
+	if err == nil {
+		r.posts[item.ID] = post.ID
+		r.merged["posts"]++
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	feedID, ok := r.feeds[item.FeedID]
+	if !ok {
+		return fmt.Errorf("post %q belongs to a feed missing from the backup", item.URL)
+	}
+	id, err := freeID(item.ID, func(id uuid.UUID) error {
+		_, err := r.s.db.GetPost(ctx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	created, err := r.s.db.CreatePost(ctx, database.CreatePostParams{
+		ID:          id,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   time.Now(),
+		PublishedAt: item.PublishedAt,
+		Title:       item.Title,
+		Url:         item.URL,
+		Description: item.Description,
+		FeedID:      feedID,
+		Author:      item.Author,
+		Categories:  item.Categories,
+	})
+	if err != nil {
+		return err
+	}
+	if item.Content != nil {
+		err = r.s.db.UpdatePostContent(ctx, database.UpdatePostContentParams{
+			ID:      created.ID,
+			Content: sql.NullString{String: *item.Content, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, enclosure := range item.Enclosures {
+		err = r.s.db.CreatePostEnclosure(ctx, database.CreatePostEnclosureParams{
+			ID:       uuid.New(),
+			PostID:   created.ID,
+			Url:      enclosure.URL,
+			MimeType: enclosure.MimeType,
+			Length:   enclosure.Length,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	r.posts[item.ID] = created.ID
+	r.added["posts"]++
+	return nil
+}
+
+// restorePostState restores a read, star or hide. They are stored with ON CONFLICT DO NOTHING,
+// so restoring one that exists already is harmless.
+func (r *restorer) restorePostState(section string, item backupPostState) error {
+	ctx := context.Background()
+	userID, postID, ok := r.userAndPost(item.UserID, item.PostID)
+	if !ok {
+		return errors.New("entry refers to a user or post missing from the backup")
+	}
+
+	var inserted int64
+	var err error
+	switch section {
+	case "post_reads":
+		inserted, err = r.s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: userID, PostID: postID, ReadAt: item.At})
+	case "post_stars":
+		inserted, err = r.s.db.StarPost(ctx, database.StarPostParams{UserID: userID, PostID: postID, StarredAt: item.At})
+	case "post_hides":
+		inserted, err = r.s.db.HidePost(ctx, database.HidePostParams{UserID: userID, PostID: postID, HiddenAt: item.At})
+	}
+	if err != nil {
+		return err
+	}
+	r.count(section, inserted)
+	return nil
+}
+
+func (r *restorer) restorePostTag(item backupPostTag) error {
+	userID, postID, ok := r.userAndPost(item.UserID, item.PostID)
+	if !ok {
+		return errors.New("post tag refers to a user or post missing from the backup")
+	}
+	inserted, err := r.s.db.TagPost(context.Background(), database.TagPostParams{UserID: userID, PostID: postID, Tag: item.Tag})
+	if err != nil {
+		return err
+	}
+	r.count("post_tags", inserted)
+	return nil
+}
+
+// count records an entry of section as added if it was inserted, or as already present
+// if the insert did nothing.
+func (r *restorer) count(section string, inserted int64) {
+	if inserted > 0 {
+		r.added[section]++
+	} else {
+		r.merged[section]++
+	}
+}
+
+func (r *restorer) restoreFilterRule(item backupFilterRule) error {
+	ctx := context.Background()
+	userID, ok := r.users[item.UserID]
+	if !ok {
+		return errors.New("filter rule refers to a user missing from the backup")
+	}
+	var feedID uuid.NullUUID
+	if item.FeedID != nil {
+		id, ok := r.feeds[*item.FeedID]
+		if !ok {
+			return errors.New("filter rule refers to a feed missing from the backup")
+		}
+		feedID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	existing, err := r.s.db.GetFilterRulesForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range existing {
+		if rule.FeedID == feedID && rule.Field == item.Field && rule.MatchType == item.MatchType &&
+			rule.Pattern == item.Pattern && rule.Action == item.Action && rule.Tag == item.Tag {
+			r.rules[item.ID] = rule.ID
+			r.merged["filter_rules"]++
+			return nil
+		}
+	}
+
+	rule, err := r.s.db.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: time.Now(),
+		UserID:    userID,
+		FeedID:    feedID,
+		Field:     item.Field,
+		MatchType: item.MatchType,
+		Pattern:   item.Pattern,
+		Action:    item.Action,
+		Tag:       item.Tag,
+	})
+	if err != nil {
+		return err
+	}
+	r.rules[item.ID] = rule.ID
+	r.added["filter_rules"]++
+	return nil
+}
+
+func (r *restorer) restoreSavedSearch(item backupSavedSearch) error {
+	ctx := context.Background()
+	userID, ok := r.users[item.UserID]
+	if !ok {
+		return errors.New("saved search refers to a user missing from the backup")
+	}
+
+	// Saving a search replaces one with the same name, which a merge shouldn't do
+	_, err := r.s.db.GetSavedSearch(ctx, database.GetSavedSearchParams{UserID: userID, Name: item.Name})
+	if err == nil {
+		r.merged["saved_searches"]++
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = r.s.db.CreateSavedSearch(ctx, database.CreateSavedSearchParams{
+		ID:        uuid.New(),
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: time.Now(),
+		UserID:    userID,
+		Name:      item.Name,
+		Query:     item.Query,
+		Language:  sql.NullString{String: item.Language, Valid: item.Language != ""},
+	})
+	if err != nil {
+		return err
+	}
+	r.added["saved_searches"]++
+	return nil
+}
+
+func (r *restorer) restoreWebhook(item backupWebhook) error {
+	ctx := context.Background()
+	userID, ok := r.users[item.UserID]
+	if !ok {
+		return errors.New("webhook refers to a user missing from the backup")
+	}
+	var feedID, filterID uuid.NullUUID
+	if item.FeedID != nil {
+		id, ok := r.feeds[*item.FeedID]
+		if !ok {
+			return errors.New("webhook refers to a feed missing from the backup")
+		}
+		feedID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if item.FilterID != nil {
+		id, ok := r.rules[*item.FilterID]
+		if !ok {
+			return errors.New("webhook refers to a filter rule missing from the backup")
+		}
+		filterID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	existing, err := r.s.db.GetWebhooksForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, hook := range existing {
+		if hook.Url == item.URL && hook.FeedID == feedID && hook.Tag == item.Tag && hook.FilterID == filterID {
+			r.merged["webhooks"]++
+			return nil
+		}
+	}
+
+	_, err = r.s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: time.Now(),
+		UserID:    userID,
+		Url:       item.URL,
+		Secret:    item.Secret,
+		FeedID:    feedID,
+		Tag:       item.Tag,
+		FilterID:  filterID,
+	})
+	if err != nil {
+		return err
+	}
+	r.added["webhooks"]++
+	return nil
+}
+
+func (r *restorer) userAndFeed(backupUserID, backupFeedID uuid.UUID) (uuid.UUID, uuid.UUID, bool) {
+	userID, userOK := r.users[backupUserID]
+	feedID, feedOK := r.feeds[backupFeedID]
+	return userID, feedID, userOK && feedOK
+}
+
+func (r *restorer) userAndPost(backupUserID, backupPostID uuid.UUID) (uuid.UUID, uuid.UUID, bool) {
+	userID, userOK := r.users[backupUserID]
+	postID, postOK := r.posts[backupPostID]
+	return userID, postID, userOK && postOK
+}
+
+// freeID returns id if lookup doesn't find a row with it, or a new random ID otherwise.
+func freeID(id uuid.UUID, lookup func(uuid.UUID) error) (uuid.UUID, error) {
+	err := lookup(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return id, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.New(), nil
+}
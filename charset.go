@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+var byteOrderMarks = []struct {
+	bom     []byte
+	charset string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+}
+
+// newFeedDecoder returns an XML decoder that yields UTF-8 whatever the feed was encoded in.
+// A byte order mark wins over the Content-Type charset, which wins over the XML declaration.
+func newFeedDecoder(body []byte, contentType string) (*xml.Decoder, error) {
+	charset, body := externalCharset(body, contentType)
+	if charset == "" {
+		// Only the XML declaration can tell, encoding/xml hands it to charsetReader
+		decoder := xml.NewDecoder(bytes.NewReader(body))
+		decoder.CharsetReader = charsetReader
+		return decoder, nil
+	}
+
+	r, err := charsetReader(charset, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	decoder := xml.NewDecoder(r)
+	// The document is UTF-8 by now, whatever its declaration claims
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder, nil
+}
+
+// charsetReader transcodes input from the named charset to UTF-8.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(strings.TrimSpace(label))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", label)
+	}
+	return transform.NewReader(input, enc.NewDecoder()), nil
+}
+
+// externalCharset reports the charset given outside the XML declaration, by a byte order mark
+// or the Content-Type header, and returns body without its byte order mark.
+func externalCharset(body []byte, contentType string) (string, []byte) {
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(body, mark.bom) {
+			return mark.charset, body[len(mark.bom):]
+		}
+	}
+
+	if contentType == "" {
+		return "", body
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", body
+	}
+	return params["charset"], body
+}
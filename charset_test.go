@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchFeedCharsets(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		contentType string
+		title       string
+		item        string
+	}{
+		{"iso-8859-1.xml", "application/rss+xml", "Café crème", "Déjà vu à Noël"},
+		{"windows-1251.xml", "application/rss+xml", "Новости", "Привет, мир"},
+		// No encoding in the XML declaration, only the header names it
+		{"shift_jis.xml", "application/rss+xml; charset=Shift_JIS", "ニュース", "こんにちは世界"},
+		{"gb2312.xml", "text/xml", "新闻", "你好世界"},
+		{"utf-16le-bom.xml", "application/xml", "Grüße", "Straße"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", "charset", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			feed, err := fetchFeed(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatalf("fetchFeed: %v", err)
+			}
+			if feed.Channel.Title != tt.title {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, tt.title)
+			}
+			if len(feed.Channel.Item) != 1 || feed.Channel.Item[0].Title != tt.item {
+				t.Errorf("items = %+v, want one titled %q", feed.Channel.Item, tt.item)
+			}
+		})
+	}
+}
+
+func TestFetchFeedUnsupportedCharset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="x-made-up"?><rss><channel><title>t</title></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	if _, err := fetchFeed(context.Background(), srv.URL); err == nil {
+		t.Error("expected an error for an unknown charset")
+	}
+}
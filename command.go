@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GLobyNew/gator/internal/config"
@@ -14,8 +20,25 @@ import (
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db    *database.Queries
+	sqlDB *sql.DB
+	cfg   *config.Config
+}
+
+// inTx calls f with a copy of s whose queries run in one transaction. The transaction is
+// committed if f succeeds and rolled back otherwise.
+func inTx(s *state, opts *sql.TxOptions, f func(tx *state) error) error {
+	tx, err := s.sqlDB.BeginTx(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	txState := *s
+	txState.db = s.db.WithTx(tx)
+	if err := f(&txState); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 type command struct {
@@ -43,6 +66,66 @@ func (c *commands) run(s *state, cmd command) error {
 	return errors.New("command not found")
 }
 
+// newFlagSet returns a flag set for cmd that reports errors instead of printing usage.
+func newFlagSet(cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags parses the flags defined on fs wherever they appear in args
+// and returns the remaining positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("command '%s': %w", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseDate accepts a date (2006-01-02) or an RFC 3339 timestamp.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// parseDateRange turns optional --since/--until values into query bounds. A bare date
+// given as until includes that whole day.
+func parseDateRange(since, until string) (sql.NullTime, sql.NullTime, error) {
+	var from, to sql.NullTime
+	if since != "" {
+		date, err := parseDate(since)
+		if err != nil {
+			return from, to, err
+		}
+		from = sql.NullTime{Time: date, Valid: true}
+	}
+	if until != "" {
+		date, err := parseDate(until)
+		if err != nil {
+			return from, to, err
+		}
+		if len(until) == len(time.DateOnly) {
+			date = date.AddDate(0, 0, 1)
+		}
+		to = sql.NullTime{Time: date, Valid: true}
+	}
+	return from, to, nil
+}
+
 func existInDB(s *state, name string) (bool, error) {
 	_, err := s.db.GetUser(context.Background(), name)
 	if err != nil {
@@ -151,13 +234,42 @@ func handlerUsers(s *state, cmd command) error {
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	force := fs.Bool("force", false, "add the feed even if it can't be fetched or parsed")
+	ingest := fs.Bool("ingest", false, "store the feed's current items right away")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
 
-	if len(cmd.args) != 2 {
-		return errors.New("command 'addfeed' expect 2 args: <name> <url>")
+	if len(args) != 2 {
+		return errors.New("command 'addfeed' expect 2 args: <name> <url> [--force] [--ingest]")
 	}
 
-	feedName := cmd.args[0]
-	feedURL := cmd.args[1]
+	feedName := args[0]
+	feedURL, err := resolveFeedURL(context.Background(), args[1], os.Stdin, os.Stdout)
+	if err != nil {
+		if !*force {
+			return err
+		}
+		fmt.Printf("warning: %v\n", err)
+		feedURL = args[1]
+	}
+
+	fetchedFeed, format, err := validateFeed(context.Background(), feedURL)
+	if err != nil {
+		if !*force {
+			return fmt.Errorf("%q isn't a valid feed (use --force to add it anyway): %w", feedURL, err)
+		}
+		fmt.Printf("warning: %q isn't a valid feed: %v\n", feedURL, err)
+	} else {
+		fmt.Printf("Format: %s\n", format)
+		fmt.Printf("Title : %s\n", fetchedFeed.Channel.Title)
+		fmt.Printf("Items : %d\n", len(fetchedFeed.Channel.Item))
+		for _, warning := range fetchedFeed.Warnings {
+			fmt.Printf("warning: %s\n", warning)
+		}
+	}
 
 	feed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
 		ID:        uuid.New(),
@@ -182,6 +294,38 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return err
 	}
 
+	if fetchedFeed != nil && len(fetchedFeed.Warnings) > 0 {
+		err = s.db.SetFeedParseWarnings(context.Background(), database.SetFeedParseWarningsParams{
+			ID:            feed.ID,
+			ParseWarnings: sql.NullString{String: strings.Join(fetchedFeed.Warnings, "; "), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if fetchedFeed != nil && fetchedFeed.Channel.Link != "" {
+		err = s.db.SetFeedSiteURL(context.Background(), database.SetFeedSiteURLParams{
+			ID:      feed.ID,
+			SiteUrl: strings.TrimSpace(fetchedFeed.Channel.Link),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *ingest && fetchedFeed != nil {
+		err = s.db.MarkFeedFetched(context.Background(), feed.ID)
+		if err != nil {
+			return err
+		}
+		stored, err := ingestFeed(s, feed, fetchedFeed)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Ingested %d new posts from %q\n", stored, feed.Name)
+	}
+
 	return nil
 
 }
@@ -201,6 +345,10 @@ func handlerFeeds(s *state, cmd command) error {
 		if err != nil {
 			return err
 		}
+		if feed.ParseWarnings.Valid {
+			fmt.Printf("* %s - %s - %s (parsed with warnings: %s)\n", feed.Name, feed.Url, user.Name, feed.ParseWarnings.String)
+			continue
+		}
 		fmt.Printf("* %s - %s - %s\n", feed.Name, feed.Url, user.Name)
 	}
 
@@ -208,6 +356,42 @@ func handlerFeeds(s *state, cmd command) error {
 
 }
 
+func handlerFullContent(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return errors.New("command 'fullcontent' expects 2 args: <feed url> <on|off>")
+	}
+
+	var enabled bool
+	switch cmd.args[1] {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return fmt.Errorf("invalid mode %q, expected 'on' or 'off'", cmd.args[1])
+	}
+
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.args[0])
+	if err != nil {
+		return err
+	}
+	// Every follower shares the setting, so only the user who added the feed may change it
+	if feed.UserID != user.ID {
+		return fmt.Errorf("only the user who added %q can change its full content fetching", feed.Name)
+	}
+
+	err = s.db.SetFeedFetchFullContent(context.Background(), database.SetFeedFetchFullContentParams{
+		ID:               feed.ID,
+		FetchFullContent: enabled,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Full content fetching for %q is %s\n", feed.Name, cmd.args[1])
+	return nil
+}
+
 func handlerFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.args) != 1 {
 		return errors.New("command 'follow' expects only one argument <url>")
@@ -219,6 +403,17 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		return err
 	}
 	feed, err := s.db.GetFeedByURL(context.Background(), url)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The URL may be a website rather than a feed, look for the feed it links to
+		feedURL, resolveErr := resolveFeedURL(context.Background(), url, os.Stdin, os.Stdout)
+		if resolveErr != nil {
+			return resolveErr
+		}
+		feed, err = s.db.GetFeedByURL(context.Background(), feedURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("feed %q hasn't been added yet, use 'addfeed' first", feedURL)
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -255,8 +450,42 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		return err
 	}
 
+	// Feeds are listed under every tag they have, untagged ones last
+	groups := make(map[string][]string)
+	var untagged []string
 	for _, follow := range following {
-		fmt.Printf("* %s\n", follow.FeedName)
+		if len(follow.Tags) == 0 {
+			untagged = append(untagged, follow.FeedName)
+		}
+		for _, tag := range follow.Tags {
+			groups[tag] = append(groups[tag], follow.FeedName)
+		}
+	}
+
+	tags := make([]string, 0, len(groups))
+	for tag := range groups {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	if len(tags) == 0 {
+		for _, name := range untagged {
+			fmt.Printf("* %s\n", name)
+		}
+		return nil
+	}
+
+	for _, tag := range tags {
+		fmt.Printf("%s:\n", tag)
+		for _, name := range groups[tag] {
+			fmt.Printf("  * %s\n", name)
+		}
+	}
+	if len(untagged) > 0 {
+		fmt.Println("untagged:")
+		for _, name := range untagged {
+			fmt.Printf("  * %s\n", name)
+		}
 	}
 
 	return nil
@@ -297,30 +526,86 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	all := fs.Bool("all", false, "include posts that were already read")
+	unread := fs.Bool("unread", false, "only show unread posts (the default)")
+	feedName := fs.String("feed", "", "only show posts of the named feed")
+	tag := fs.String("tag", "", "only show posts of feeds with this tag")
+	since := fs.String("since", "", "only show posts published on or after this date")
+	until := fs.String("until", "", "only show posts published up to this date")
+	order := fs.String("order", "oldest", "sort order: newest|oldest")
+	offset := fs.Int("offset", 0, "number of posts to skip")
+	search := fs.String("search", "", "only show posts containing this text")
+	searchName := fs.String("search-name", "", "only show posts matching the named saved search")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if *all && *unread {
+		return errors.New("command 'browse' accepts only one of --all and --unread")
+	}
+	if *order != "newest" && *order != "oldest" {
+		return fmt.Errorf("invalid order %q, expected 'newest' or 'oldest'", *order)
+	}
+	if *offset < 0 {
+		return fmt.Errorf("invalid offset value: %d", *offset)
+	}
+
 	var limit int32
-	if len(cmd.args) == 1 {
-		parsedLimit, err := strconv.Atoi(cmd.args[0])
+	if len(args) == 1 {
+		parsedLimit, err := strconv.Atoi(args[0])
 		if err != nil {
 			return fmt.Errorf("invalid limit value: %v", err)
 		}
+		if parsedLimit <= 0 || parsedLimit > math.MaxInt32 {
+			return fmt.Errorf("invalid limit value: %d", parsedLimit)
+		}
 		limit = int32(parsedLimit)
 	} else {
 		limit = 2
 	}
 
+	params := database.BrowsePostsParams{
+		UserID:      user.ID,
+		IncludeRead: *all,
+		FeedName:    sql.NullString{String: *feedName, Valid: *feedName != ""},
+		Tag:         sql.NullString{String: *tag, Valid: *tag != ""},
+		Search:      sql.NullString{String: *search, Valid: *search != ""},
+		NewestFirst: *order == "newest",
+		PostLimit:   limit,
+		PostOffset:  int32(*offset),
+	}
+	params.Since, params.Until, err = parseDateRange(*since, *until)
+	if err != nil {
+		return err
+	}
+	if *searchName != "" {
+		tsquery, config, err := savedSearchQuery(s, user, *searchName)
+		if err != nil {
+			return err
+		}
+		params.Tsquery = sql.NullString{String: tsquery, Valid: true}
+		params.SearchConfig = config
+	}
 
-	posts, err := s.db.GetPostsByUser(context.Background(), database.GetPostsByUserParams{
-		ID:    user.ID,
-		Limit: limit,
-	})
+	posts, err := s.db.BrowsePosts(context.Background(), params)
 	if err != nil {
 		return err
 	}
 
 	for _, post := range posts {
+		id, err := postShortID(s, user, post.ID)
+		if err != nil {
+			return err
+		}
 		fmt.Println("--------------------------------------------------")
+		fmt.Printf("ID          : %s\n", id)
 		fmt.Printf("Title       : %s\n", post.Title)
 		fmt.Printf("Description : %s\n", post.Description)
+		if post.Content.Valid {
+			fmt.Printf("Content     :\n%s\n", post.Content.String)
+		}
 		fmt.Printf("Published At: %s\n", post.PublishedAt)
 		fmt.Printf("Feed        : %s\n", post.FeedName)
 		fmt.Println("--------------------------------------------------")
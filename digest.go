@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GLobyNew/gator/internal/database"
+)
+
+const (
+	digestFormatText     = "text"
+	digestFormatMarkdown = "markdown"
+	digestFormatHTML     = "html"
+)
+
+// digestExcerptLength is the number of characters of a post's text shown in a digest.
+const digestExcerptLength = 280
+
+const untaggedGroup = "untagged"
+
+// digest is the new posts of a user's followed feeds, grouped by tag and then by feed.
+type digest struct {
+	User   string
+	Since  time.Time
+	Until  time.Time
+	Posts  int
+	Groups []digestGroup
+}
+
+// digestGroup holds the feeds of a tag. Tag is empty when the user hasn't tagged any feeds.
+type digestGroup struct {
+	Tag   string
+	Feeds []digestFeed
+}
+
+type digestFeed struct {
+	Name  string
+	Posts []digestPost
+}
+
+type digestPost struct {
+	ID          string
+	Title       string
+	URL         string
+	Author      string
+	PublishedAt time.Time
+	Excerpt     string
+}
+
+func handlerDigest(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	period := fs.String("period", "24h", "how far back the digest goes, e.g. 24h or 7d")
+	format := fs.String("format", digestFormatText, "output format: text|markdown|html")
+	output := fs.String("o", "", "file to write to instead of standard output")
+	dryRun := fs.Bool("dry-run", false, "don't record the digest, so the next one shows the same posts")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("command 'digest' expects flags only: [--period <duration>] [--format text|markdown|html] [-o <file>] [--dry-run]")
+	}
+	if *format != digestFormatText && *format != digestFormatMarkdown && *format != digestFormatHTML {
+		return fmt.Errorf("invalid format %q, expected text, markdown or html", *format)
+	}
+	duration, err := parsePeriod(*period)
+	if err != nil {
+		return err
+	}
+
+	d, err := buildDigest(s, user, user.LastDigestAt, duration, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = writeExport(*output, func(w io.Writer) error {
+		return writeDigest(w, d, *format)
+	})
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		return nil
+	}
+	return recordDigest(s, user, d)
+}
+
+// parsePeriod parses a duration, also accepting a number of days such as "7d".
+func parsePeriod(value string) (time.Duration, error) {
+	var period time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid period %q: %w", value, err)
+		}
+		period = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		period, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid period %q: %w", value, err)
+		}
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("invalid period %q: must be positive", value)
+	}
+	return period, nil
+}
+
+// buildDigest collects the posts fetched in the period before now. Posts fetched before
+// last, the end of the previous digest, are left out, so consecutive digests don't repeat
+// posts. Digests printed by 'digest' and mailed ones are counted separately.
+func buildDigest(s *state, user database.User, last sql.NullTime, period time.Duration, now time.Time) (digest, error) {
+	since := now.Add(-period)
+	if last.Valid && last.Time.After(since) {
+		since = last.Time
+	}
+
+	posts, err := s.db.GetDigestPosts(context.Background(), database.GetDigestPostsParams{
+		UserID: user.ID,
+		Since:  since,
+		Until:  now,
+	})
+	if err != nil {
+		return digest{}, err
+	}
+
+	d := digest{User: user.Name, Since: since, Until: now, Posts: len(posts)}
+
+	// Posts are listed under every tag of their feed, untagged ones last. Posts come
+	// ordered by feed, so each feed's posts are consecutive.
+	feedsByTag := make(map[string][]digestFeed)
+	tagged := false
+	for _, post := range posts {
+		id, err := postShortID(s, user, post.ID)
+		if err != nil {
+			return digest{}, err
+		}
+		item := digestPost{
+			ID:          id,
+			Title:       post.Title,
+			URL:         post.Url,
+			Author:      post.Author,
+			PublishedAt: post.PublishedAt,
+			Excerpt:     postExcerpt(post.Description, post.Content),
+		}
+		tags := post.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedGroup}
+		} else {
+			tagged = true
+		}
+		for _, tag := range tags {
+			feeds := feedsByTag[tag]
+			if len(feeds) == 0 || feeds[len(feeds)-1].Name != post.FeedName {
+				feeds = append(feeds, digestFeed{Name: post.FeedName})
+			}
+			feeds[len(feeds)-1].Posts = append(feeds[len(feeds)-1].Posts, item)
+			feedsByTag[tag] = feeds
+		}
+	}
+
+	if !tagged {
+		if feeds, ok := feedsByTag[untaggedGroup]; ok {
+			d.Groups = []digestGroup{{Feeds: feeds}}
+		}
+		return d, nil
+	}
+
+	tags := make([]string, 0, len(feedsByTag))
+	for tag := range feedsByTag {
+		if tag != untaggedGroup {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	if _, ok := feedsByTag[untaggedGroup]; ok {
+		tags = append(tags, untaggedGroup)
+	}
+	for _, tag := range tags {
+		d.Groups = append(d.Groups, digestGroup{Tag: tag, Feeds: feedsByTag[tag]})
+	}
+	return d, nil
+}
+
+// recordDigest remembers when the user's digest was made, so the next one starts from there.
+func recordDigest(s *state, user database.User, d digest) error {
+	return s.db.SetUserLastDigest(context.Background(), database.SetUserLastDigestParams{
+		ID:           user.ID,
+		LastDigestAt: sql.NullTime{Time: d.Until, Valid: true},
+	})
+}
+
+// postExcerpt returns the start of a post's description, or of its full content when the
+// description is empty, as plain text.
+func postExcerpt(description string, content sql.NullString) string {
+	text := collapseSpace(htmlToText(description))
+	if text == "" && content.Valid {
+		text = collapseSpace(content.String)
+	}
+	if utf8.RuneCountInString(text) <= digestExcerptLength {
+		return text
+	}
+
+	runes := []rune(text)[:digestExcerptLength]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+func writeDigest(w io.Writer, d digest, format string) error {
+	switch format {
+	case digestFormatMarkdown:
+		return writeDigestMarkdown(w, d)
+	case digestFormatHTML:
+		return digestHTMLTemplate.Execute(w, d)
+	default:
+		return writeDigestText(w, d)
+	}
+}
+
+func (d digest) Title() string {
+	return fmt.Sprintf("Digest for %s, %s to %s", d.User, d.Since.Format("2006-01-02 15:04"), d.Until.Format("2006-01-02 15:04"))
+}
+
+func (d digest) Summary() string {
+	if d.Posts == 1 {
+		return "1 new post"
+	}
+	return fmt.Sprintf("%d new posts", d.Posts)
+}
+
+func writeDigestText(w io.Writer, d digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n", d.Title(), d.Summary())
+	for _, group := range d.Groups {
+		indent := ""
+		if group.Tag != "" {
+			fmt.Fprintf(&b, "\n%s:\n", group.Tag)
+			indent = "  "
+		}
+		for _, feed := range group.Feeds {
+			fmt.Fprintf(&b, "\n%s%s\n", indent, feed.Name)
+			for _, post := range feed.Posts {
+				fmt.Fprintf(&b, "%s  * %s [%s]\n", indent, post.Title, post.ID)
+				fmt.Fprintf(&b, "%s    %s\n", indent, post.URL)
+				if post.Excerpt != "" {
+					fmt.Fprintf(&b, "%s    %s\n", indent, post.Excerpt)
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeDigestMarkdown(w io.Writer, d digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n", d.Title(), d.Summary())
+	feedHeading := "##"
+	if len(d.Groups) > 0 && d.Groups[0].Tag != "" {
+		feedHeading = "###"
+	}
+	for _, group := range d.Groups {
+		if group.Tag != "" {
+			fmt.Fprintf(&b, "\n## %s\n", group.Tag)
+		}
+		for _, feed := range group.Feeds {
+			fmt.Fprintf(&b, "\n%s %s\n\n", feedHeading, feed.Name)
+			for _, post := range feed.Posts {
+				fmt.Fprintf(&b, "- [%s](%s)", post.Title, post.URL)
+				if post.Author != "" {
+					fmt.Fprintf(&b, " by %s", post.Author)
+				}
+				b.WriteString("\n")
+				if post.Excerpt != "" {
+					fmt.Fprintf(&b, "  %s\n", post.Excerpt)
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var digestHTMLTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 48em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+.meta { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Summary}}</p>
+{{range .Groups}}{{if .Tag}}<h2>{{.Tag}}</h2>
+{{end}}{{range .Feeds}}<h3>{{.Name}}</h3>
+<ul>
+{{range .Posts}}<li><a href="{{.URL}}">{{.Title}}</a>{{if .Author}} <span class="meta">by {{.Author}}</span>{{end}}
+{{- if .Excerpt}}<br>{{.Excerpt}}{{end}}</li>
+{{end}}</ul>
+{{end}}{{end}}</body>
+</html>
+`))
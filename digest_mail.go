@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/GLobyNew/gator/internal/mailer"
+)
+
+const (
+	digestScheduleDaily  = "daily"
+	digestScheduleWeekly = "weekly"
+)
+
+// digestSchedule parses how often a user's digest is mailed: daily, weekly or a period
+// as accepted by parsePeriod.
+func digestSchedule(value string) (time.Duration, error) {
+	switch value {
+	case digestScheduleDaily:
+		return 24 * time.Hour, nil
+	case digestScheduleWeekly:
+		return 7 * 24 * time.Hour, nil
+	default:
+		return parsePeriod(value)
+	}
+}
+
+func handlerEmail(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return errors.New("command 'email' expects a subcommand: set | show | off | send")
+	}
+
+	sub := command{name: "email " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "set":
+		return handlerEmailSet(s, sub, user)
+	case "show":
+		return handlerEmailShow(s, sub, user)
+	case "off":
+		return handlerEmailOff(s, sub, user)
+	case "send":
+		return handlerEmailSend(s, sub, user)
+	default:
+		return fmt.Errorf("unknown 'email' subcommand %q, expected set | show | off | send", cmd.args[0])
+	}
+}
+
+func handlerEmailSet(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	schedule := fs.String("schedule", digestScheduleDaily, "how often the digest is mailed: daily|weekly|<period>")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("command 'email set' expects one argument: <address> [--schedule daily|weekly|<period>]")
+	}
+	address, err := mail.ParseAddress(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %w", args[0], err)
+	}
+	if _, err := digestSchedule(*schedule); err != nil {
+		return err
+	}
+
+	err = s.db.SetUserDigestEmail(context.Background(), database.SetUserDigestEmailParams{
+		ID:             user.ID,
+		Email:          address.String(),
+		DigestSchedule: *schedule,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Digests will be mailed %s to %s\n", scheduleDescription(*schedule), address)
+	if s.cfg.SMTP == nil {
+		fmt.Println("No SMTP server is configured yet, add one to the config file to have them sent")
+	}
+	return nil
+}
+
+func handlerEmailShow(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("command 'email show' doesn't expect arguments")
+	}
+
+	if user.Email == "" || user.DigestSchedule == "" {
+		fmt.Println("Digests aren't mailed to you")
+		return nil
+	}
+	fmt.Printf("Address    : %s\n", user.Email)
+	fmt.Printf("Schedule   : %s\n", user.DigestSchedule)
+	if user.LastMailedAt.Valid {
+		fmt.Printf("Last mailed: %s\n", user.LastMailedAt.Time.Format("2006-01-02 15:04"))
+	}
+	if s.cfg.SMTP == nil {
+		fmt.Println("No SMTP server is configured, digests won't be sent")
+	}
+	return nil
+}
+
+func handlerEmailOff(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("command 'email off' doesn't expect arguments")
+	}
+
+	err := s.db.SetUserDigestEmail(context.Background(), database.SetUserDigestEmailParams{ID: user.ID})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Digests won't be mailed to you anymore")
+	return nil
+}
+
+func handlerEmailSend(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("command 'email send' doesn't expect arguments")
+	}
+	if s.cfg.SMTP == nil {
+		return errors.New("no SMTP server is configured")
+	}
+	if user.Email == "" {
+		return errors.New("no email address set, use 'email set <address>' first")
+	}
+	period, err := digestSchedule(user.DigestSchedule)
+	if err != nil {
+		return err
+	}
+
+	d, err := mailDigest(s, user, period, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if d.Posts == 0 {
+		fmt.Println("Nothing new, no mail sent")
+		return nil
+	}
+	fmt.Printf("Mailed a digest of %s to %s\n", d.Summary(), user.Email)
+	return nil
+}
+
+// sendDueDigests mails the digest of every user whose schedule says one is due. It does
+// nothing unless an SMTP server is configured. A failure for one user doesn't stop the
+// others from getting theirs.
+func sendDueDigests(s *state, now time.Time) error {
+	if s.cfg.SMTP == nil {
+		return nil
+	}
+
+	users, err := s.db.GetUsersWithDigestEmail(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		period, err := digestSchedule(user.DigestSchedule)
+		if err != nil {
+			fmt.Printf("Skipping digest of %s: %v\n", user.Name, err)
+			continue
+		}
+		if user.LastMailedAt.Valid && now.Sub(user.LastMailedAt.Time) < period {
+			continue
+		}
+
+		d, err := mailDigest(s, user, period, now)
+		if err != nil {
+			fmt.Printf("Couldn't mail digest of %s: %v\n", user.Name, err)
+			continue
+		}
+		if d.Posts > 0 {
+			fmt.Printf("Mailed a digest of %s to %s\n", d.Summary(), user.Email)
+		}
+	}
+
+	return nil
+}
+
+// mailDigest builds the user's digest, mails it unless it is empty and records it. It is
+// recorded first, in a transaction committed once the mail was sent: a failed mail leaves
+// the digest due, and a digest that can't be recorded isn't mailed on every tick.
+func mailDigest(s *state, user database.User, period time.Duration, now time.Time) (digest, error) {
+	d, err := buildDigest(s, user, user.LastMailedAt, period, now)
+	if err != nil {
+		return digest{}, err
+	}
+
+	err = inTx(s, nil, func(tx *state) error {
+		err := tx.db.SetUserLastMailed(context.Background(), database.SetUserLastMailedParams{
+			ID:           user.ID,
+			LastMailedAt: sql.NullTime{Time: d.Until, Valid: true},
+		})
+		if err != nil || d.Posts == 0 {
+			return err
+		}
+
+		var text, html bytes.Buffer
+		if err := writeDigest(&text, d, digestFormatText); err != nil {
+			return err
+		}
+		if err := writeDigest(&html, d, digestFormatHTML); err != nil {
+			return err
+		}
+		return mailer.Send(*s.cfg.SMTP, mailer.Message{
+			To:      user.Email,
+			Subject: d.Title(),
+			Text:    text.String(),
+			HTML:    html.String(),
+		})
+	})
+	if err != nil {
+		return digest{}, err
+	}
+	return d, nil
+}
+
+func scheduleDescription(schedule string) string {
+	switch schedule {
+	case digestScheduleDaily, digestScheduleWeekly:
+		return schedule
+	default:
+		return "every " + schedule
+	}
+}
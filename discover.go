@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Paths probed when a page doesn't advertise its feed with <link> tags.
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/index.xml"}
+
+// Only RSS feeds can be parsed, so pages' feeds of other types are left out.
+const rssLinkType = "application/rss+xml"
+
+var unsupportedFeedLinkTypes = map[string]string{
+	"application/atom+xml":  formatAtom,
+	"application/feed+json": formatJSONFeed,
+}
+
+type feedCandidate struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// resolveFeedURL returns rawURL unchanged when it points at a feed. When it points at an
+// HTML page, the feeds the page links to are discovered and the single match is used;
+// with several matches the user picks one from in.
+func resolveFeedURL(ctx context.Context, rawURL string, in io.Reader, out io.Writer) (string, error) {
+	body, isHTML, err := fetchPage(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	if !isHTML {
+		return rawURL, nil
+	}
+
+	candidates, err := discoverFeeds(ctx, rawURL, body)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("%q is a web page and no feed could be found on it", rawURL)
+	case 1:
+		fmt.Fprintf(out, "%q is a web page, using its feed %s\n", rawURL, candidates[0].URL)
+		return candidates[0].URL, nil
+	}
+
+	fmt.Fprintf(out, "%q is a web page with several feeds:\n", rawURL)
+	for i, c := range candidates {
+		fmt.Fprintf(out, "%d) %s (%s) %s\n", i+1, c.Title, c.Type, c.URL)
+	}
+	return pickCandidate(candidates, in, out)
+}
+
+func pickCandidate(candidates []feedCandidate, in io.Reader, out io.Writer) (string, error) {
+	fmt.Fprintf(out, "Pick a feed [1-%d]: ", len(candidates))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no feed picked")
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return "", fmt.Errorf("invalid choice %q", strings.TrimSpace(line))
+	}
+	return candidates[choice-1].URL, nil
+}
+
+// discoverFeeds collects the RSS feeds advertised by <link rel="alternate"> tags in page,
+// falling back to probing common feed paths on the same host.
+func discoverFeeds(ctx context.Context, pageURL string, page []byte) ([]feedCandidate, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+
+	if baseTag := findFirst(doc, atom.Base); baseTag != nil {
+		if href, err := base.Parse(attr(baseTag, "href")); err == nil {
+			base = href
+		}
+	}
+
+	var candidates []feedCandidate
+	var unsupported []string
+	seen := make(map[string]bool)
+	walk(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Link {
+			return
+		}
+		if !hasToken(attr(n, "rel"), "alternate") {
+			return
+		}
+		linkType := strings.ToLower(strings.TrimSpace(attr(n, "type")))
+		if format, ok := unsupportedFeedLinkTypes[linkType]; ok {
+			if !slices.Contains(unsupported, format) {
+				unsupported = append(unsupported, format)
+			}
+			return
+		}
+		if linkType != rssLinkType {
+			return
+		}
+		href, err := base.Parse(attr(n, "href"))
+		if err != nil || seen[href.String()] {
+			return
+		}
+		seen[href.String()] = true
+		candidates = append(candidates, feedCandidate{
+			URL:   href.String(),
+			Title: strings.TrimSpace(attr(n, "title")),
+			Type:  linkType,
+		})
+	})
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		probe := base.ResolveReference(&url.URL{Path: path}).String()
+		body, isHTML, err := fetchPage(ctx, probe)
+		if err != nil || isHTML || detectFeedFormat(body, "") != formatRSS2 {
+			continue
+		}
+		candidates = append(candidates, feedCandidate{URL: probe, Title: path, Type: "probed"})
+	}
+	if len(candidates) == 0 && len(unsupported) > 0 {
+		return nil, fmt.Errorf("%q only advertises %s feeds, which gator can't read", pageURL, strings.Join(unsupported, " and "))
+	}
+	return candidates, nil
+}
+
+// fetchPage downloads pageURL and reports whether the response is an HTML document.
+func fetchPage(ctx context.Context, pageURL string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("User-Agent", "gator")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unexpected status fetching %q: %s", pageURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	if isHTML && looksLikeFeed(body) {
+		// Some servers send feeds as text/html.
+		isHTML = false
+	}
+	return body, isHTML, nil
+}
+
+func looksLikeFeed(body []byte) bool {
+	head := strings.ToLower(string(body[:min(len(body), 512)]))
+	return strings.Contains(head, "<rss") || strings.Contains(head, "<feed") ||
+		strings.Contains(head, "<rdf:rdf") || strings.Contains(head, "jsonfeed.org/version")
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title></channel></rss>`
+
+// newDiscoverServer serves body with contentType for every path in pages and 404 for the rest.
+func newDiscoverServer(t *testing.T, pages map[string][2]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", page[0])
+		io.WriteString(w, page[1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveFeedURLDiscoversFeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		extra   map[string][2]string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "single RSS link",
+			page: `<html><head><link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.xml"></head></html>`,
+			want: "/posts.xml",
+		},
+		{
+			name: "Atom and JSON links are left out",
+			page: `<html><head>
+				<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+				<link rel="alternate" type="application/feed+json" href="/feed.json">
+				<link rel="alternate" type="application/rss+xml" href="/rss.xml">
+			</head></html>`,
+			want: "/rss.xml",
+		},
+		{
+			name: "base and relative hrefs",
+			page: `<html><head><base href="/blog/"><link rel="alternate stylesheet" type="text/css" href="style.css"><link rel="Alternate" type="Application/RSS+XML" href="feed.xml"></head></html>`,
+			want: "/blog/feed.xml",
+		},
+		{
+			name:  "several RSS links ask which",
+			page:  `<html><head><link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.xml"><link rel="alternate" type="application/rss+xml" title="Comments" href="/comments.xml"></head></html>`,
+			input: "2\n",
+			want:  "/comments.xml",
+		},
+		{
+			name:    "invalid choice",
+			page:    `<html><head><link rel="alternate" type="application/rss+xml" href="/a.xml"><link rel="alternate" type="application/rss+xml" href="/b.xml"></head></html>`,
+			input:   "3\n",
+			wantErr: "invalid choice",
+		},
+		{
+			name:  "probes common paths",
+			page:  `<html><head><title>No links</title></head></html>`,
+			extra: map[string][2]string{"/rss.xml": {"application/xml", testRSS}},
+			want:  "/rss.xml",
+		},
+		{
+			name: "probing skips Atom",
+			page: `<html><head><title>No links</title></head></html>`,
+			extra: map[string][2]string{
+				"/feed":    {"application/xml", `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"></feed>`},
+				"/rss.xml": {"application/xml", testRSS},
+			},
+			want: "/rss.xml",
+		},
+		{
+			name:    "only unsupported feeds",
+			page:    `<html><head><link rel="alternate" type="application/atom+xml" href="/atom.xml"></head></html>`,
+			wantErr: "only advertises Atom feeds",
+		},
+		{
+			name:    "no feeds",
+			page:    `<html><head><title>Nothing</title></head></html>`,
+			wantErr: "no feed could be found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := map[string][2]string{"/": {"text/html; charset=utf-8", tt.page}}
+			for path, page := range tt.extra {
+				pages[path] = page
+			}
+			srv := newDiscoverServer(t, pages)
+
+			got, err := resolveFeedURL(context.Background(), srv.URL+"/", strings.NewReader(tt.input), io.Discard)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveFeedURL: %v", err)
+			}
+			if got != srv.URL+tt.want {
+				t.Errorf("resolved %q, want %q", got, srv.URL+tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFeedURLKeepsFeeds(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"RSS", "application/rss+xml", testRSS},
+		{"RSS sent as HTML", "text/html", testRSS},
+		{"Atom", "application/atom+xml", `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"></feed>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDiscoverServer(t, map[string][2]string{"/feed.xml": {tt.contentType, tt.body}})
+
+			got, err := resolveFeedURL(context.Background(), srv.URL+"/feed.xml", strings.NewReader(""), io.Discard)
+			if err != nil {
+				t.Fatalf("resolveFeedURL: %v", err)
+			}
+			if got != srv.URL+"/feed.xml" {
+				t.Errorf("resolved %q, want the feed's own URL", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Images larger than this are left out of the book.
+const maxEPUBImageSize = 10 << 20
+
+// Elements kept in chapters; other elements are replaced by their content.
+var epubAllowedTags = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Br:         true,
+	atom.Hr:         true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Li:         true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.Code:       true,
+	atom.Em:         true,
+	atom.Strong:     true,
+	atom.B:          true,
+	atom.I:          true,
+	atom.U:          true,
+	atom.Sub:        true,
+	atom.Sup:        true,
+	atom.A:          true,
+	atom.Img:        true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Table:      true,
+	atom.Thead:      true,
+	atom.Tbody:      true,
+	atom.Tr:         true,
+	atom.Th:         true,
+	atom.Td:         true,
+}
+
+// Image types every EPUB 3 reader supports, with the extension used for them in the book.
+var epubImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+const epubStyle = `body { font-family: serif; line-height: 1.5; }
+h1 { font-size: 1.4em; }
+.meta { color: #666; font-size: 0.9em; }
+img { max-width: 100%; }
+`
+
+type epubChapter struct {
+	File  string
+	Title string
+	Feed  string
+}
+
+type epubImage struct {
+	File      string
+	MediaType string
+}
+
+// epubWriter bundles exported posts into an EPUB 3 book, one chapter per post.
+type epubWriter struct {
+	zw          *zip.Writer
+	title       string
+	fetchImages bool
+	chapters    []epubChapter
+	images      []epubImage
+	imageFiles  map[string]string
+}
+
+func handlerExportEPUB(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	unread := fs.Bool("unread", false, "only export unread posts")
+	starred := fs.Bool("starred", false, "only export starred posts")
+	feedName := fs.String("feed", "", "only export posts of the named feed")
+	tag := fs.String("tag", "", "only export posts of feeds with this tag")
+	since := fs.String("since", "", "only export posts published on or after this date")
+	until := fs.String("until", "", "only export posts published up to this date")
+	images := fs.Bool("images", false, "download the images of posts into the book")
+	title := fs.String("title", "", "title of the book")
+	output := fs.String("o", "", "file to write the book to")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 || *output == "" {
+		return errors.New("command 'export epub' expects an output file: -o <file> [--unread] [--starred] [--feed <name>] [--tag <tag>] [--since <date>] [--until <date>] [--images] [--title <title>]")
+	}
+
+	params := database.ExportPostsParams{
+		UserID:      user.ID,
+		StarredOnly: *starred,
+		UnreadOnly:  *unread,
+		FeedName:    sql.NullString{String: *feedName, Valid: *feedName != ""},
+		Tag:         sql.NullString{String: *tag, Valid: *tag != ""},
+		PostLimit:   exportBatchSize,
+	}
+	params.Since, params.Until, err = parseDateRange(*since, *until)
+	if err != nil {
+		return err
+	}
+
+	if *title == "" {
+		*title = fmt.Sprintf("gator: %s", time.Now().Format("2006-01-02"))
+	}
+
+	var exported int
+	err = writeExport(*output, func(w io.Writer) error {
+		ew := &epubWriter{
+			zw:          zip.NewWriter(w),
+			title:       *title,
+			fetchImages: *images,
+			imageFiles:  make(map[string]string),
+		}
+		var err error
+		exported, err = streamPosts(s, params, ew)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d posts to %s\n", exported, *output)
+	return nil
+}
+
+func (e *epubWriter) begin() error {
+	// The mimetype must come first and be stored uncompressed, without extra fields
+	mimetype := []byte("application/epub+zip")
+	w, err := e.zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(mimetype); err != nil {
+		return err
+	}
+
+	err = e.writeFile("META-INF/container.xml", xml.Header+`<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`)
+	if err != nil {
+		return err
+	}
+	return e.writeFile("OEBPS/style.css", epubStyle)
+}
+
+func (e *epubWriter) write(post exportedPost) error {
+	title := post.Title
+	if strings.TrimSpace(title) == "" {
+		title = "Untitled"
+	}
+	chapter := epubChapter{
+		File:  fmt.Sprintf("chapter-%04d.xhtml", len(e.chapters)+1),
+		Title: title,
+		Feed:  post.Feed,
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<h1>%s</h1>\n", xmlEscape(title))
+	fmt.Fprintf(&body, "<p class=\"meta\">%s, %s", xmlEscape(post.Feed), post.PublishedAt.Format("2006-01-02 15:04"))
+	if post.Author != "" {
+		fmt.Fprintf(&body, ", %s", xmlEscape(post.Author))
+	}
+	body.WriteString("</p>\n")
+	if post.Content != "" {
+		for _, paragraph := range strings.Split(post.Content, "\n\n") {
+			fmt.Fprintf(&body, "<p>%s</p>\n", xmlEscape(paragraph))
+		}
+	} else {
+		fmt.Fprintf(&body, "<div>%s</div>\n", e.sanitize(post.Description, post.URL))
+	}
+	if post.URL != "" {
+		fmt.Fprintf(&body, "<p class=\"meta\"><a href=\"%s\">Original article</a></p>\n", xmlEscape(post.URL))
+	}
+
+	if err := e.writeFile("OEBPS/"+chapter.File, xhtmlPage(title, body.String())); err != nil {
+		return err
+	}
+	e.chapters = append(e.chapters, chapter)
+	return nil
+}
+
+func (e *epubWriter) end() error {
+	if len(e.chapters) == 0 {
+		return errors.New("no posts to export")
+	}
+
+	// Chapters are grouped by feed in the table of contents and in reading order
+	chapters := make([]epubChapter, len(e.chapters))
+	copy(chapters, e.chapters)
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Feed < chapters[j].Feed
+	})
+
+	var nav strings.Builder
+	nav.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>Contents</h1>\n<ol>\n")
+	for i, chapter := range chapters {
+		if i == 0 || chapters[i-1].Feed != chapter.Feed {
+			fmt.Fprintf(&nav, "<li><span>%s</span>\n<ol>\n", xmlEscape(chapter.Feed))
+		}
+		fmt.Fprintf(&nav, "<li><a href=\"%s\">%s</a></li>\n", chapter.File, xmlEscape(chapter.Title))
+		if i == len(chapters)-1 || chapters[i+1].Feed != chapter.Feed {
+			nav.WriteString("</ol>\n</li>\n")
+		}
+	}
+	nav.WriteString("</ol>\n</nav>\n")
+	if err := e.writeFile("OEBPS/nav.xhtml", xhtmlPage("Contents", nav.String())); err != nil {
+		return err
+	}
+
+	var opf strings.Builder
+	opf.WriteString(xml.Header)
+	opf.WriteString("<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\" unique-identifier=\"book-id\" xml:lang=\"en\">\n")
+	opf.WriteString("  <metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	fmt.Fprintf(&opf, "    <dc:identifier id=\"book-id\">urn:uuid:%s</dc:identifier>\n", uuid.New())
+	fmt.Fprintf(&opf, "    <dc:title>%s</dc:title>\n", xmlEscape(e.title))
+	opf.WriteString("    <dc:language>en</dc:language>\n")
+	opf.WriteString("    <dc:creator>gator</dc:creator>\n")
+	fmt.Fprintf(&opf, "    <meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	opf.WriteString("  </metadata>\n  <manifest>\n")
+	opf.WriteString("    <item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	opf.WriteString("    <item id=\"style\" href=\"style.css\" media-type=\"text/css\"/>\n")
+	for _, chapter := range chapters {
+		fmt.Fprintf(&opf, "    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", strings.TrimSuffix(chapter.File, ".xhtml"), chapter.File)
+	}
+	for i, image := range e.images {
+		fmt.Fprintf(&opf, "    <item id=\"image-%04d\" href=\"%s\" media-type=\"%s\"/>\n", i+1, image.File, image.MediaType)
+	}
+	opf.WriteString("  </manifest>\n  <spine>\n    <itemref idref=\"nav\"/>\n")
+	for _, chapter := range chapters {
+		fmt.Fprintf(&opf, "    <itemref idref=\"%s\"/>\n", strings.TrimSuffix(chapter.File, ".xhtml"))
+	}
+	opf.WriteString("  </spine>\n</package>\n")
+	if err := e.writeFile("OEBPS/content.opf", opf.String()); err != nil {
+		return err
+	}
+
+	return e.zw.Close()
+}
+
+func (e *epubWriter) writeFile(name, contents string) error {
+	w, err := e.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, contents)
+	return err
+}
+
+// sanitize turns an HTML fragment from a feed into XHTML that only uses the elements in
+// epubAllowedTags and safe links. Images are embedded when enabled, or replaced by their
+// alt text.
+func (e *epubWriter) sanitize(fragment, pageURL string) string {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return "<p>" + xmlEscape(fragment) + "</p>"
+	}
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return ""
+	}
+	base, _ := url.Parse(pageURL)
+
+	var sb strings.Builder
+	var render func(*html.Node)
+	render = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				sb.WriteString(xmlEscape(c.Data))
+			case c.Type != html.ElementNode || strippedTags[c.DataAtom]:
+			case !epubAllowedTags[c.DataAtom]:
+				render(c)
+			case c.DataAtom == atom.Img:
+				if src, ok := e.embedImage(resolveURL(base, attr(c, "src"))); ok {
+					fmt.Fprintf(&sb, "<img src=\"%s\" alt=\"%s\"/>", src, xmlEscape(attr(c, "alt")))
+				} else if alt := attr(c, "alt"); alt != "" {
+					sb.WriteString(xmlEscape(alt))
+				}
+			case c.DataAtom == atom.A:
+				href := resolveURL(base, attr(c, "href"))
+				if href == "" {
+					render(c)
+					continue
+				}
+				fmt.Fprintf(&sb, "<a href=\"%s\">", xmlEscape(href))
+				render(c)
+				sb.WriteString("</a>")
+			case c.DataAtom == atom.Br || c.DataAtom == atom.Hr:
+				fmt.Fprintf(&sb, "<%s/>", c.Data)
+			default:
+				fmt.Fprintf(&sb, "<%s>", c.Data)
+				render(c)
+				fmt.Fprintf(&sb, "</%s>", c.Data)
+			}
+		}
+	}
+	render(body)
+	return sb.String()
+}
+
+// embedImage downloads the image at imageURL into the book, once per URL, and returns its
+// path relative to the chapters.
+func (e *epubWriter) embedImage(imageURL string) (string, bool) {
+	if !e.fetchImages || !strings.HasPrefix(imageURL, "http") {
+		return "", false
+	}
+	if file, ok := e.imageFiles[imageURL]; ok {
+		return file, file != ""
+	}
+	// Failed downloads are remembered too, so they aren't retried for every chapter
+	e.imageFiles[imageURL] = ""
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return "", false
+	}
+	req.Header.Set("User-Agent", "gator")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("couldn't download image %q: %v\n", imageURL, err)
+		return "", false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("couldn't download image %q: %s\n", imageURL, resp.Status)
+		return "", false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEPUBImageSize+1))
+	if err != nil {
+		fmt.Printf("couldn't download image %q: %v\n", imageURL, err)
+		return "", false
+	}
+	if len(data) > maxEPUBImageSize {
+		fmt.Printf("skipping image %q: larger than %d bytes\n", imageURL, maxEPUBImageSize)
+		return "", false
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if _, ok := epubImageTypes[mediaType]; !ok {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	ext, ok := epubImageTypes[mediaType]
+	if !ok {
+		fmt.Printf("skipping image %q: unsupported type %q\n", imageURL, mediaType)
+		return "", false
+	}
+
+	file := fmt.Sprintf("images/image-%04d%s", len(e.images)+1, ext)
+	if err := e.writeFile("OEBPS/"+file, string(data)); err != nil {
+		fmt.Printf("couldn't store image %q: %v\n", imageURL, err)
+		return "", false
+	}
+	e.images = append(e.images, epubImage{File: file, MediaType: mediaType})
+	e.imageFiles[imageURL] = file
+	return file, true
+}
+
+// resolveURL makes ref absolute against base and returns it if it is a web or mail link.
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || ref == "" {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return u.String()
+	}
+	return ""
+}
+
+func xhtmlPage(title, body string) string {
+	return xml.Header + `<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">
+<head>
+<meta charset="utf-8"/>
+<title>` + xmlEscape(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `</body>
+</html>
+`
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmptyEPUBLeavesNoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.epub")
+	err := writeExport(path, func(w io.Writer) error {
+		ew := &epubWriter{zw: zip.NewWriter(w), title: "Empty", imageFiles: make(map[string]string)}
+		if err := ew.begin(); err != nil {
+			return err
+		}
+		return ew.end()
+	})
+	if err == nil {
+		t.Fatal("exporting a book without posts succeeded")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a failed export left %s behind: %v", path, err)
+	}
+}
+
+// epubNav is the part of nav.xhtml the table of contents is read from.
+type epubNav struct {
+	Feeds []struct {
+		Name     string `xml:"span"`
+		Chapters []struct {
+			Href  string `xml:"href,attr"`
+			Title string `xml:",chardata"`
+		} `xml:"ol>li>a"`
+	} `xml:"body>nav>ol>li"`
+}
+
+type epubPackage struct {
+	Title    string `xml:"metadata>title"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func TestEPUBWriter(t *testing.T) {
+	var pixel bytes.Buffer
+	if err := png.Encode(&pixel, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pixel.Bytes())
+	}))
+	defer srv.Close()
+
+	posts := []exportedPost{
+		{
+			Title:       "Scripts & links",
+			URL:         srv.URL + "/posts/1",
+			Feed:        "Zeta news",
+			PublishedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			Description: `<p>Hello <script>alert("hi")</script><a href="javascript:alert(1)">click</a> <a href="/more">more</a><br><img src="/pixel.png" alt="a pixel" onerror="alert(2)"></p>`,
+		},
+		{
+			Title:       "Full article",
+			URL:         srv.URL + "/posts/2",
+			Feed:        "Alpha blog",
+			Author:      "Alice",
+			PublishedAt: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC),
+			Content:     "First paragraph.\n\nSecond <paragraph> & more.",
+		},
+		{
+			Title:       "Same pixel",
+			URL:         srv.URL + "/posts/3",
+			Feed:        "Zeta news",
+			PublishedAt: time.Date(2024, 3, 3, 10, 0, 0, 0, time.UTC),
+			Description: `<div onclick="steal()"><img src="` + srv.URL + `/pixel.png" alt="again"></div>`,
+		},
+	}
+
+	var buf bytes.Buffer
+	ew := &epubWriter{zw: zip.NewWriter(&buf), title: "Gators & feeds", fetchImages: true, imageFiles: make(map[string]string)}
+	if err := ew.begin(); err != nil {
+		t.Fatal(err)
+	}
+	for _, post := range posts {
+		if err := ew.write(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ew.end(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := zr.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("first entry is %s (method %d), want an uncompressed mimetype", first.Name, first.Method)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = data
+	}
+
+	// Every page must be well-formed XHTML
+	chapters := make(map[string]string)
+	for name, data := range files {
+		if !strings.HasSuffix(name, ".xhtml") {
+			continue
+		}
+		var page struct {
+			XMLName xml.Name `xml:"http://www.w3.org/1999/xhtml html"`
+			Body    struct {
+				Inner string `xml:",innerxml"`
+			} `xml:"body"`
+		}
+		if err := xml.Unmarshal(data, &page); err != nil {
+			t.Errorf("%s isn't well-formed: %v\n%s", name, err, data)
+			continue
+		}
+		chapters[strings.TrimPrefix(name, "OEBPS/")] = page.Body.Inner
+	}
+
+	unsafe := chapters["chapter-0001.xhtml"]
+	for _, unwanted := range []string{"<script", "alert", "javascript:", "onerror", "/pixel.png"} {
+		if strings.Contains(unsafe, unwanted) {
+			t.Errorf("sanitized chapter contains %q:\n%s", unwanted, unsafe)
+		}
+	}
+	for _, want := range []string{`<a href="` + srv.URL + `/more">more</a>`, `<br/>`, `<img src="images/image-0001.png" alt="a pixel"/>`} {
+		if !strings.Contains(unsafe, want) {
+			t.Errorf("sanitized chapter is missing %q:\n%s", want, unsafe)
+		}
+	}
+	if got := chapters["chapter-0002.xhtml"]; !strings.Contains(got, "<p>Second &lt;paragraph&gt; &amp; more.</p>") {
+		t.Errorf("full content chapter isn't split into escaped paragraphs:\n%s", got)
+	}
+	if got := chapters["chapter-0003.xhtml"]; strings.Contains(got, "steal") || !strings.Contains(got, `<img src="images/image-0001.png" alt="again"/>`) {
+		t.Errorf("chapter doesn't reuse the embedded image:\n%s", got)
+	}
+
+	var nav epubNav
+	if err := xml.Unmarshal(files["OEBPS/nav.xhtml"], &nav); err != nil {
+		t.Fatal(err)
+	}
+	var toc []string
+	for _, feed := range nav.Feeds {
+		for _, chapter := range feed.Chapters {
+			toc = append(toc, feed.Name+": "+chapter.Href+" "+chapter.Title)
+		}
+	}
+	wantTOC := []string{
+		"Alpha blog: chapter-0002.xhtml Full article",
+		"Zeta news: chapter-0001.xhtml Scripts & links",
+		"Zeta news: chapter-0003.xhtml Same pixel",
+	}
+	if !slices.Equal(toc, wantTOC) || len(nav.Feeds) != 2 {
+		t.Errorf("table of contents = %q in %d groups, want %q in 2", toc, len(nav.Feeds), wantTOC)
+	}
+
+	var pkg epubPackage
+	if err := xml.Unmarshal(files["OEBPS/content.opf"], &pkg); err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Title != "Gators & feeds" {
+		t.Errorf("title = %q", pkg.Title)
+	}
+	var manifest []string
+	for _, item := range pkg.Manifest {
+		manifest = append(manifest, item.Href+" "+item.MediaType)
+		if _, ok := files["OEBPS/"+item.Href]; !ok {
+			t.Errorf("manifest lists %s, which isn't in the book", item.Href)
+		}
+	}
+	wantManifest := []string{
+		"nav.xhtml application/xhtml+xml",
+		"style.css text/css",
+		"chapter-0002.xhtml application/xhtml+xml",
+		"chapter-0001.xhtml application/xhtml+xml",
+		"chapter-0003.xhtml application/xhtml+xml",
+		"images/image-0001.png image/png",
+	}
+	if !slices.Equal(manifest, wantManifest) {
+		t.Errorf("manifest = %q, want %q", manifest, wantManifest)
+	}
+	var spine []string
+	for _, ref := range pkg.Spine {
+		spine = append(spine, ref.IDRef)
+	}
+	if want := []string{"nav", "chapter-0002", "chapter-0001", "chapter-0003"}; !slices.Equal(spine, want) {
+		t.Errorf("spine = %q, want %q", spine, want)
+	}
+}
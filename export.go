@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GLobyNew/gator/internal/database"
+)
+
+func handlerExport(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return errors.New("command 'export' expects what to export: opml | posts | epub | feed")
+	}
+
+	sub := command{name: "export " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "opml":
+		return handlerExportOPML(s, sub, user)
+	case "posts":
+		return handlerExportPosts(s, sub, user)
+	case "epub":
+		return handlerExportEPUB(s, sub, user)
+	case "feed":
+		return handlerExportFeed(s, sub, user)
+	default:
+		return fmt.Errorf("unknown 'export' kind %q, expected opml | posts | epub | feed", cmd.args[0])
+	}
+}
+
+func handlerExportOPML(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	tag := fs.String("tag", "", "only export feeds with this tag")
+	output := fs.String("o", "", "file to write to instead of standard output")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("command 'export opml' doesn't expect arguments: [--tag <tag>] [-o <file>]")
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return writeExport(*output, func(w io.Writer) error {
+		return writeOPML(w, user, follows, *tag)
+	})
+}
+
+// writeExport runs write against the file at path, or standard output when path is empty.
+// The file is removed again if write fails, so no partial export is left behind.
+func writeExport(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
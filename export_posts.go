@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Posts are read from the database in batches of this size while they are exported.
+const exportBatchSize = 100
+
+var exportPostFormats = []string{"md", "json", "csv", "html"}
+
+type exportedPost struct {
+	ID          string              `json:"id"`
+	Title       string              `json:"title"`
+	URL         string              `json:"url"`
+	Feed        string              `json:"feed"`
+	FeedURL     string              `json:"feed_url"`
+	Author      string              `json:"author"`
+	Categories  []string            `json:"categories"`
+	PublishedAt time.Time           `json:"published_at"`
+	FetchedAt   time.Time           `json:"fetched_at"`
+	Read        bool                `json:"read"`
+	Starred     bool                `json:"starred"`
+	Description string              `json:"description"`
+	Content     string              `json:"content,omitempty"`
+	Enclosures  []exportedEnclosure `json:"enclosures"`
+}
+
+type exportedEnclosure struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Length   int64  `json:"length"`
+}
+
+// text is the post's full content, or its description as plain text when there is none.
+func (p exportedPost) text() string {
+	if p.Content != "" {
+		return p.Content
+	}
+	return htmlToText(p.Description)
+}
+
+// postWriter writes exported posts one at a time in some format.
+type postWriter interface {
+	begin() error
+	write(post exportedPost) error
+	end() error
+}
+
+func handlerExportPosts(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	format := fs.String("format", "", "output format: md|json|csv|html")
+	feedName := fs.String("feed", "", "only export posts of the named feed")
+	tag := fs.String("tag", "", "only export posts of feeds with this tag")
+	since := fs.String("since", "", "only export posts published on or after this date")
+	until := fs.String("until", "", "only export posts published up to this date")
+	starred := fs.Bool("starred", false, "only export starred posts")
+	split := fs.Bool("split", false, "with --format md, write one file per post into the directory given by -o")
+	output := fs.String("o", "", "file to write to instead of standard output")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("command 'export posts' expects flags only: --format md|json|csv|html [--feed <name>] [--tag <tag>] [--since <date>] [--until <date>] [--starred] [--split] [-o <path>]")
+	}
+	if !slices.Contains(exportPostFormats, *format) {
+		return fmt.Errorf("invalid format %q, expected md, json, csv or html", *format)
+	}
+	if *split && (*format != "md" || *output == "") {
+		return errors.New("--split needs --format md and a directory given with -o")
+	}
+
+	params := database.ExportPostsParams{
+		UserID:      user.ID,
+		StarredOnly: *starred,
+		FeedName:    sql.NullString{String: *feedName, Valid: *feedName != ""},
+		Tag:         sql.NullString{String: *tag, Valid: *tag != ""},
+		PostLimit:   exportBatchSize,
+	}
+	params.Since, params.Until, err = parseDateRange(*since, *until)
+	if err != nil {
+		return err
+	}
+
+	var exported int
+	if *split {
+		if err := os.MkdirAll(*output, 0o755); err != nil {
+			return err
+		}
+		exported, err = streamPosts(s, params, &markdownDirWriter{dir: *output})
+	} else {
+		err = writeExport(*output, func(w io.Writer) error {
+			var pw postWriter
+			switch *format {
+			case "md":
+				pw = &markdownPostWriter{w: w}
+			case "json":
+				pw = &jsonPostWriter{w: w}
+			case "csv":
+				pw = &csvPostWriter{w: csv.NewWriter(w)}
+			case "html":
+				pw = &htmlPostWriter{w: w, user: user.Name}
+			}
+			var err error
+			exported, err = streamPosts(s, params, pw)
+			return err
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output != "" {
+		fmt.Printf("Exported %d posts to %s\n", exported, *output)
+	}
+	return nil
+}
+
+// streamPosts reads the posts matching params batch by batch and hands them to pw.
+// It returns how many posts were written.
+func streamPosts(s *state, params database.ExportPostsParams, pw postWriter) (int, error) {
+	if err := pw.begin(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		posts, err := s.db.ExportPosts(context.Background(), params)
+		if err != nil {
+			return count, err
+		}
+
+		for _, post := range posts {
+			enclosures, err := s.db.GetPostEnclosures(context.Background(), post.ID)
+			if err != nil {
+				return count, err
+			}
+
+			exported := exportedPost{
+				ID:          post.ID.String(),
+				Title:       post.Title,
+				URL:         post.Url,
+				Feed:        post.FeedName,
+				FeedURL:     post.FeedUrl,
+				Author:      post.Author,
+				Categories:  post.Categories,
+				PublishedAt: post.PublishedAt,
+				FetchedAt:   post.CreatedAt,
+				Read:        post.IsRead,
+				Starred:     post.IsStarred,
+				Description: post.Description,
+				Content:     post.Content.String,
+				Enclosures:  []exportedEnclosure{},
+			}
+			for _, enclosure := range enclosures {
+				exported.Enclosures = append(exported.Enclosures, exportedEnclosure{
+					URL:      enclosure.Url,
+					MimeType: enclosure.MimeType,
+					Length:   enclosure.Length,
+				})
+			}
+
+			if err := pw.write(exported); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(posts) < int(params.PostLimit) {
+			break
+		}
+		last := posts[len(posts)-1]
+		params.AfterPublishedAt = sql.NullTime{Time: last.PublishedAt, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+
+	return count, pw.end()
+}
+
+type jsonPostWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonPostWriter) begin() error {
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonPostWriter) write(post exportedPost) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("  ", "  ")
+	if err := encoder.Encode(post); err != nil {
+		return err
+	}
+	separator := "\n  "
+	if j.count > 0 {
+		separator = ",\n  "
+	}
+	j.count++
+	_, err := fmt.Fprintf(j.w, "%s%s", separator, bytes.TrimRight(buf.Bytes(), "\n"))
+	return err
+}
+
+func (j *jsonPostWriter) end() error {
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+type csvPostWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvPostWriter) begin() error {
+	return c.w.Write([]string{"id", "title", "url", "feed", "feed_url", "author", "categories", "published_at", "fetched_at", "read", "starred", "enclosures", "description", "content"})
+}
+
+func (c *csvPostWriter) write(post exportedPost) error {
+	enclosures := make([]string, 0, len(post.Enclosures))
+	for _, enclosure := range post.Enclosures {
+		enclosures = append(enclosures, enclosure.URL)
+	}
+	return c.w.Write([]string{
+		post.ID,
+		post.Title,
+		post.URL,
+		post.Feed,
+		post.FeedURL,
+		post.Author,
+		strings.Join(post.Categories, "; "),
+		post.PublishedAt.Format(time.RFC3339),
+		post.FetchedAt.Format(time.RFC3339),
+		strconv.FormatBool(post.Read),
+		strconv.FormatBool(post.Starred),
+		strings.Join(enclosures, " "),
+		post.Description,
+		post.Content,
+	})
+}
+
+func (c *csvPostWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type markdownPostWriter struct {
+	w io.Writer
+}
+
+func (m *markdownPostWriter) begin() error {
+	return nil
+}
+
+func (m *markdownPostWriter) write(post exportedPost) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## [%s](%s)\n\n", post.Title, post.URL)
+	fmt.Fprintf(&sb, "*%s, %s*", post.Feed, post.PublishedAt.Format("2006-01-02 15:04"))
+	if post.Author != "" {
+		fmt.Fprintf(&sb, " *by %s*", post.Author)
+	}
+	sb.WriteString("\n\n")
+	if len(post.Categories) > 0 {
+		fmt.Fprintf(&sb, "Categories: %s\n\n", strings.Join(post.Categories, ", "))
+	}
+	if text := post.text(); text != "" {
+		fmt.Fprintf(&sb, "%s\n\n", text)
+	}
+	for _, enclosure := range post.Enclosures {
+		fmt.Fprintf(&sb, "- [%s](%s) (%s)\n", filepath.Base(enclosure.URL), enclosure.URL, enclosure.MimeType)
+	}
+	sb.WriteString("\n---\n\n")
+	_, err := io.WriteString(m.w, sb.String())
+	return err
+}
+
+func (m *markdownPostWriter) end() error {
+	return nil
+}
+
+// markdownDirWriter writes every post to a Markdown file of its own, with its metadata as
+// YAML front matter.
+type markdownDirWriter struct {
+	dir string
+}
+
+func (m *markdownDirWriter) begin() error {
+	return nil
+}
+
+func (m *markdownDirWriter) write(post exportedPost) error {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "id: %s\n", strconv.Quote(post.ID))
+	fmt.Fprintf(&sb, "title: %s\n", strconv.Quote(post.Title))
+	fmt.Fprintf(&sb, "url: %s\n", strconv.Quote(post.URL))
+	fmt.Fprintf(&sb, "feed: %s\n", strconv.Quote(post.Feed))
+	fmt.Fprintf(&sb, "feed_url: %s\n", strconv.Quote(post.FeedURL))
+	fmt.Fprintf(&sb, "author: %s\n", strconv.Quote(post.Author))
+	fmt.Fprintf(&sb, "categories: %s\n", quoteList(post.Categories))
+	fmt.Fprintf(&sb, "published_at: %s\n", post.PublishedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "fetched_at: %s\n", post.FetchedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "read: %t\n", post.Read)
+	fmt.Fprintf(&sb, "starred: %t\n", post.Starred)
+	enclosures := make([]string, 0, len(post.Enclosures))
+	for _, enclosure := range post.Enclosures {
+		enclosures = append(enclosures, enclosure.URL)
+	}
+	fmt.Fprintf(&sb, "enclosures: %s\n", quoteList(enclosures))
+	sb.WriteString("---\n\n")
+	fmt.Fprintf(&sb, "# %s\n\n", post.Title)
+	if text := post.text(); text != "" {
+		fmt.Fprintf(&sb, "%s\n", text)
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.md", post.PublishedAt.Format("2006-01-02"), slugify(post.Title), post.ID[:shortIDLength])
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(sb.String()), 0o644)
+}
+
+func (m *markdownDirWriter) end() error {
+	return nil
+}
+
+// quoteList formats values as a YAML flow sequence of double-quoted strings.
+func quoteList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// slugify turns a title into a lowercase, dash-separated file name part.
+func slugify(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slug := strings.Join(words, "-")
+	if runes := []rune(slug); len(runes) > 60 {
+		slug = strings.TrimRight(string(runes[:60]), "-")
+	}
+	if slug == "" {
+		slug = "post"
+	}
+	return slug
+}
+
+var postsHTMLTemplate = template.Must(template.New("posts").Parse(`
+{{- define "begin" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Posts of {{.}}</title>
+<style>
+body { max-width: 48em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+article { border-bottom: 1px solid #ccc; padding-bottom: 1em; }
+.meta { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Posts of {{.}}</h1>
+{{end -}}
+{{- define "post" -}}
+<article id="post-{{.ID}}">
+<h2><a href="{{.URL}}">{{.Title}}</a></h2>
+<p class="meta">{{.Feed}} &middot; <time datetime="{{.PublishedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.PublishedAt.Format "2006-01-02 15:04"}}</time>
+{{- if .Author}} &middot; {{.Author}}{{end}}
+{{- if .Categories}} &middot; {{range $i, $c := .Categories}}{{if $i}}, {{end}}{{$c}}{{end}}{{end}}
+{{- if .Starred}} &middot; &#9733;{{end}}</p>
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end -}}
+{{range .Enclosures}}<p class="meta"><a href="{{.URL}}">{{.URL}}</a> ({{.MimeType}})</p>
+{{end -}}
+</article>
+{{end -}}
+{{- define "end" -}}
+</body>
+</html>
+{{end -}}
+`))
+
+// htmlPostWriter writes posts as a single HTML page. Posts are rendered from their plain
+// text, so no markup from the feeds ends up in the page.
+type htmlPostWriter struct {
+	w    io.Writer
+	user string
+}
+
+func (h *htmlPostWriter) begin() error {
+	return postsHTMLTemplate.ExecuteTemplate(h.w, "begin", h.user)
+}
+
+func (h *htmlPostWriter) write(post exportedPost) error {
+	var paragraphs []string
+	if text := post.text(); text != "" {
+		paragraphs = strings.Split(text, "\n\n")
+	}
+	return postsHTMLTemplate.ExecuteTemplate(h.w, "post", struct {
+		exportedPost
+		Paragraphs []string
+	}{post, paragraphs})
+}
+
+func (h *htmlPostWriter) end() error {
+	return postsHTMLTemplate.ExecuteTemplate(h.w, "end", nil)
+}
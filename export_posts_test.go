@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testExportedPosts are the posts the export golden files in testdata/export were made from.
+var testExportedPosts = []exportedPost{
+	{
+		ID:          "1a2b3c4d-5e6f-4a8b-9c0d-1e2f3a4b5c6d",
+		Title:       `Say "hello", world`,
+		URL:         "https://example.com/posts/1?a=1&b=2",
+		Feed:        "Alice's blog",
+		FeedURL:     "https://alice.example.com/feed.xml",
+		Author:      "Alice, B.",
+		Categories:  []string{"go", "tips; tricks", `say "hi"`, "café"},
+		PublishedAt: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+		FetchedAt:   time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Read:        true,
+		Description: "<p>First line,\nsecond line</p>",
+		Content:     "First line.\n\nSecond line with a \"quote\".",
+		Enclosures: []exportedEnclosure{
+			{URL: "https://example.com/a.mp3", MimeType: "audio/mpeg", Length: 1024},
+			{URL: "https://example.com/b.jpg", MimeType: "image/jpeg"},
+		},
+	},
+	{
+		ID:          "9f8e7d6c-5b4a-4c3d-8e2f-1a0b9c8d7e6f",
+		Title:       "Plain",
+		URL:         "https://example.com/posts/2",
+		Feed:        "Alice's blog",
+		FeedURL:     "https://alice.example.com/feed.xml",
+		PublishedAt: time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC),
+		FetchedAt:   time.Date(2024, 3, 2, 8, 5, 0, 0, time.UTC),
+		Starred:     true,
+		Description: "Plain text",
+	},
+}
+
+func TestCSVPostWriterGolden(t *testing.T) {
+	var buf bytes.Buffer
+	pw := &csvPostWriter{w: csv.NewWriter(&buf)}
+	if err := pw.begin(); err != nil {
+		t.Fatal(err)
+	}
+	for _, post := range testExportedPosts {
+		if err := pw.write(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.end(); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("testdata", "export", "posts.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("CSV export:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	// Whatever the escaping, the fields must read back unchanged
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1][1] != testExportedPosts[0].Title || records[1][12] != testExportedPosts[0].Description {
+		t.Errorf("read back %q", records)
+	}
+}
+
+func TestMarkdownDirWriterGolden(t *testing.T) {
+	dir := t.TempDir()
+	pw := &markdownDirWriter{dir: dir}
+	if err := pw.write(testExportedPosts[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	const name = "2024-03-01-say-hello-world-1a2b3c4d.md"
+	got, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "export", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Markdown export:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestQuoteList(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{nil, "[]"},
+		{[]string{"go"}, `["go"]`},
+		{[]string{"a, b", `say "hi"`, `back\slash`}, `["a, b", "say \"hi\"", "back\\slash"]`},
+		{[]string{"tab\there", "line\nbreak"}, `["tab\there", "line\nbreak"]`},
+	}
+	for _, tt := range tests {
+		if got := quoteList(tt.values); got != tt.want {
+			t.Errorf("quoteList(%q) = %s, want %s", tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// maxArticleSize caps how much of a page is read to extract its article.
+const maxArticleSize = 5 << 20
+
+// articleTimeout bounds downloading a page, so a server that hangs can't stall the aggregator.
+var articleTimeout = 30 * time.Second
+
+var (
+	unlikelyCandidate = regexp.MustCompile(`(?i)comment|sidebar|footer|header|nav|menu|share|social|promo|sponsor|related|banner|popup|cookie|subscribe`)
+	likelyCandidate   = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text`)
+)
+
+// Tags that never hold article text and are dropped before scoring.
+var strippedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Svg:      true,
+}
+
+// Tags whose text becomes a separate paragraph in the extracted content.
+var blockTags = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Li:         true,
+	atom.Pre:        true,
+	atom.Blockquote: true,
+}
+
+// Containers that separate loose text into paragraphs of their own.
+var breakTags = map[atom.Atom]bool{
+	atom.Div:     true,
+	atom.Section: true,
+	atom.Article: true,
+	atom.Br:      true,
+	atom.Hr:      true,
+	atom.Table:   true,
+	atom.Tr:      true,
+	atom.Ul:      true,
+	atom.Ol:      true,
+	atom.Figure:  true,
+}
+
+// extractArticle downloads the page at pageURL and returns the text of its main content.
+// Pages are decoded to UTF-8 from the charset given by their Content-Type or <meta> tags.
+func extractArticle(ctx context.Context, pageURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, articleTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("User-Agent", "gator")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status fetching %q: %s", pageURL, resp.Status)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxArticleSize), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", err
+	}
+	return extractMainContent(body)
+}
+
+// extractMainContent picks the element most likely to hold the article body,
+// scoring paragraphs the way readability does, and returns its text with
+// paragraphs separated by blank lines.
+func extractMainContent(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return "", errors.New("page has no body")
+	}
+	pruneNodes(body)
+
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = classWeight(n)
+			switch n.DataAtom {
+			case atom.Article, atom.Main:
+				scores[n] += 10
+			case atom.Div:
+				scores[n] += 5
+			}
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	walk(body, func(n *html.Node) {
+		if n.Type != html.ElementNode || (n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td) {
+			return
+		}
+		text := strings.TrimSpace(nodeText(n))
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	})
+
+	var best *html.Node
+	var bestScore float64
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		best = body
+	}
+
+	content := renderParagraphs(best)
+	if content == "" {
+		return "", errors.New("no readable content found")
+	}
+	return content, nil
+}
+
+// htmlToText renders an HTML fragment, such as a feed item's description, as plain text.
+func htmlToText(fragment string) string {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return fragment
+	}
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return fragment
+	}
+	pruneNodes(body)
+	return renderParagraphs(body)
+}
+
+// pruneNodes removes elements that are unlikely to be part of the article.
+func pruneNodes(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isUnlikely(c)) {
+			n.RemoveChild(c)
+		} else {
+			pruneNodes(c)
+		}
+		c = next
+	}
+}
+
+func isUnlikely(n *html.Node) bool {
+	if strippedTags[n.DataAtom] {
+		return true
+	}
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	hint := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidate.MatchString(hint) && !likelyCandidate.MatchString(hint)
+}
+
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, hint := range []string{attr(n, "class"), attr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if likelyCandidate.MatchString(hint) {
+			weight += 25
+		}
+		if unlikelyCandidate.MatchString(hint) {
+			weight -= 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of n's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(strings.TrimSpace(nodeText(n)))
+	if total == 0 {
+		return 0
+	}
+	var linked int
+	walk(n, func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linked += len(strings.TrimSpace(nodeText(c)))
+		}
+	})
+	return float64(linked) / float64(total)
+}
+
+func renderParagraphs(n *html.Node) string {
+	var paragraphs []string
+	var loose strings.Builder
+	flush := func() {
+		if text := collapseSpace(loose.String()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		loose.Reset()
+	}
+
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		switch {
+		case c.Type == html.TextNode:
+			loose.WriteString(c.Data)
+			return
+		case c.Type == html.ElementNode && blockTags[c.DataAtom]:
+			flush()
+			if text := collapseSpace(nodeText(c)); text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+			return
+		}
+		breaks := c.Type == html.ElementNode && breakTags[c.DataAtom]
+		if breaks {
+			flush()
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+		if breaks {
+			flush()
+		}
+	}
+	visit(n)
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	})
+	return sb.String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func walk(n *html.Node, f func(*html.Node)) {
+	f(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, f)
+	}
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) {
+		if found == nil && c.Type == html.ElementNode && c.DataAtom == a {
+			found = c
+		}
+	})
+	return found
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
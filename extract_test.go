@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html>
+<head><title>Example post</title><script>var tracking = "nope";</script></head>
+<body>
+  <nav><a href="/">Home</a> <a href="/about">About</a> <a href="/archive">Archive</a></nav>
+  <div class="sidebar">
+    <p>Subscribe to our newsletter, follow us everywhere, and never miss a single post again.</p>
+  </div>
+  <div class="post-content">
+    <h1>Why gators love RSS</h1>
+    <p>Feeds are a simple, durable way to follow writers, and they don't depend on any single platform.</p>
+    <p>Readers choose what to subscribe to, in which order to read it, and when to stop caring.</p>
+    <p>That independence is exactly why the format keeps outliving the services that try to replace it.</p>
+  </div>
+  <footer><p>Copyright gators, all rights reserved, forever and ever and ever.</p></footer>
+</body>
+</html>`
+
+func TestExtractArticle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(articlePage))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	content, err := extractArticle(context.Background(), srv.URL+"/post")
+	if err != nil {
+		t.Fatalf("extractArticle: %v", err)
+	}
+
+	for _, want := range []string{
+		"Why gators love RSS",
+		"Feeds are a simple, durable way to follow writers",
+		"That independence is exactly why the format keeps outliving",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content is missing %q:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"Archive", "newsletter", "Copyright", "tracking"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("content contains boilerplate %q:\n%s", unwanted, content)
+		}
+	}
+	if got := strings.Count(content, "\n\n"); got != 3 {
+		t.Errorf("expected 4 paragraphs, got %d:\n%s", got+1, content)
+	}
+
+	if _, err := extractArticle(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("expected an error for a missing page")
+	}
+}
+
+func TestExtractArticleDecodesCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		meta        string
+		enc         encoding.Encoding
+		text        string
+	}{
+		{"windows-1251 header", "text/html; charset=windows-1251", "", charmap.Windows1251, "Почему аллигаторы любят RSS, ленты и спокойное чтение без спешки."},
+		{"shift_jis meta", "text/html", `<meta charset="Shift_JIS">`, japanese.ShiftJIS, "ワニはフィード、購読、そして静かな読書がとても好きです。ずっと読みます。"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := fmt.Sprintf("<html><head>%s</head><body><div class=\"post\"><p>%s</p></div></body></html>", tt.meta, tt.text)
+			encoded, err := tt.enc.NewEncoder().String(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(encoded))
+			}))
+			defer srv.Close()
+
+			content, err := extractArticle(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatalf("extractArticle: %v", err)
+			}
+			if content != tt.text {
+				t.Errorf("content = %q, want %q", content, tt.text)
+			}
+		})
+	}
+}
+
+func TestExtractArticleTimesOut(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	defer func(old time.Duration) { articleTimeout = old }(articleTimeout)
+	articleTimeout = 50 * time.Millisecond
+
+	if _, err := extractArticle(context.Background(), srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want a deadline error", err)
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	filterActionHide     = "hide"
+	filterActionMarkRead = "mark-read"
+	filterActionStar     = "star"
+	filterActionTag      = "tag"
+)
+
+var (
+	filterFields  = []string{"title", "content", "author", "category"}
+	filterMatches = []string{"keyword", "regex"}
+	filterActions = []string{filterActionHide, filterActionMarkRead, filterActionStar, filterActionTag}
+)
+
+// filteredPost holds what filter rules can match a post on.
+type filteredPost struct {
+	ID          uuid.UUID
+	FeedID      uuid.UUID
+	Title       string
+	Description string
+	Content     string
+	Author      string
+	Categories  []string
+}
+
+type compiledRule struct {
+	database.FilterRule
+	re *regexp.Regexp
+}
+
+func compileRules(rules []database.FilterRule) []compiledRule {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c := compiledRule{FilterRule: rule}
+		if rule.MatchType == "regex" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				fmt.Printf("skipping filter %s: %v\n", shortID(rule.ID), err)
+				continue
+			}
+			c.re = re
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+func (r compiledRule) matches(post filteredPost) bool {
+	if r.FeedID.Valid && r.FeedID.UUID != post.FeedID {
+		return false
+	}
+
+	var values []string
+	switch r.Field {
+	case "title":
+		values = []string{post.Title}
+	case "content":
+		values = []string{post.Description, post.Content}
+	case "author":
+		values = []string{post.Author}
+	case "category":
+		values = post.Categories
+	}
+
+	for _, value := range values {
+		switch {
+		case r.re != nil:
+			if r.re.MatchString(value) {
+				return true
+			}
+		case r.Field == "category":
+			if strings.EqualFold(value, r.Pattern) {
+				return true
+			}
+		default:
+			if strings.Contains(strings.ToLower(value), strings.ToLower(r.Pattern)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyFilterRules runs the actions of every rule matching post, on behalf of the rule's owner.
+// It returns how many rules matched.
+func applyFilterRules(s *state, rules []compiledRule, post filteredPost) (int, error) {
+	matched := 0
+	for _, rule := range rules {
+		if !rule.matches(post) {
+			continue
+		}
+		matched++
+
+		var err error
+		switch rule.Action {
+		case filterActionHide:
+			_, err = s.db.HidePost(context.Background(), database.HidePostParams{
+				UserID:   rule.UserID,
+				PostID:   post.ID,
+				HiddenAt: time.Now(),
+			})
+		case filterActionMarkRead:
+			_, err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+				UserID: rule.UserID,
+				PostID: post.ID,
+				ReadAt: time.Now(),
+			})
+		case filterActionStar:
+			_, err = s.db.StarPost(context.Background(), database.StarPostParams{
+				UserID:    rule.UserID,
+				PostID:    post.ID,
+				StarredAt: time.Now(),
+			})
+		case filterActionTag:
+			_, err = s.db.TagPost(context.Background(), database.TagPostParams{
+				UserID: rule.UserID,
+				PostID: post.ID,
+				Tag:    rule.Tag,
+			})
+		}
+		if err != nil {
+			return matched, err
+		}
+	}
+	return matched, nil
+}
+
+func handlerFilter(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return errors.New("command 'filter' expects a subcommand: add | list | rm")
+	}
+
+	sub := command{name: "filter " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "add":
+		return handlerFilterAdd(s, sub, user)
+	case "list":
+		return handlerFilterList(s, sub, user)
+	case "rm":
+		return handlerFilterRemove(s, sub, user)
+	default:
+		return fmt.Errorf("unknown 'filter' subcommand %q, expected add | list | rm", cmd.args[0])
+	}
+}
+
+func handlerFilterAdd(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	field := fs.String("field", "title", "what to match: "+strings.Join(filterFields, "|"))
+	match := fs.String("match", "keyword", "how to match: "+strings.Join(filterMatches, "|"))
+	action := fs.String("action", "", "what to do with matching posts: "+strings.Join(filterActions, "|"))
+	tag := fs.String("tag", "", "tag given to matching posts by the 'tag' action")
+	feedName := fs.String("feed", "", "only apply to posts of the named feed")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New("command 'filter add' expects a pattern: <pattern> --action <action> [--field <field>] [--match keyword|regex] [--tag <tag>] [--feed <name>]")
+	}
+	pattern := strings.Join(args, " ")
+
+	if !slices.Contains(filterFields, *field) {
+		return fmt.Errorf("invalid field %q, expected one of %s", *field, strings.Join(filterFields, ", "))
+	}
+	if !slices.Contains(filterMatches, *match) {
+		return fmt.Errorf("invalid match %q, expected one of %s", *match, strings.Join(filterMatches, ", "))
+	}
+	if !slices.Contains(filterActions, *action) {
+		return fmt.Errorf("invalid action %q, expected one of %s", *action, strings.Join(filterActions, ", "))
+	}
+	if (*action == filterActionTag) != (*tag != "") {
+		return errors.New("--tag is required by the 'tag' action and only allowed with it")
+	}
+	if *match == "regex" {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	var feedID uuid.NullUUID
+	if *feedName != "" {
+		feed, err := s.db.GetFeed(context.Background(), *feedName)
+		if err != nil {
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	rule, err := s.db.CreateFilterRule(context.Background(), database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Field:     *field,
+		MatchType: *match,
+		Pattern:   pattern,
+		Action:    *action,
+		Tag:       *tag,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Added filter %s\n", shortID(rule.ID))
+	return nil
+}
+
+func handlerFilterList(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("command 'filter list' doesn't expect arguments")
+	}
+
+	rules, err := s.db.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		action := rule.Action
+		if rule.Action == filterActionTag {
+			action += " #" + rule.Tag
+		}
+		scope := "all feeds"
+		if rule.FeedName.Valid {
+			scope = rule.FeedName.String
+		}
+		fmt.Printf("* %s - %s %s %q -> %s (%s)\n", shortID(rule.ID), rule.Field, rule.MatchType, rule.Pattern, action, scope)
+	}
+
+	return nil
+}
+
+func handlerFilterRemove(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'filter rm' expects only one argument: <filter id>")
+	}
+
+	rule, err := findFilterRule(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.DeleteFilterRule(context.Background(), database.DeleteFilterRuleParams{
+		ID:     rule.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed filter %s\n", shortID(rule.ID))
+	return nil
+}
+
+// findFilterRule returns the user's filter rule whose ID starts with ref.
+func findFilterRule(s *state, user database.User, ref string) (database.GetFilterRulesForUserRow, error) {
+	if len(ref) < minIDPrefixLength {
+		return database.GetFilterRulesForUserRow{}, fmt.Errorf("filter ID %q is too short, type at least %d characters of it", ref, minIDPrefixLength)
+	}
+
+	rules, err := s.db.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.GetFilterRulesForUserRow{}, err
+	}
+
+	var found []database.GetFilterRulesForUserRow
+	for _, rule := range rules {
+		if strings.HasPrefix(rule.ID.String(), strings.ToLower(ref)) {
+			found = append(found, rule)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return database.GetFilterRulesForUserRow{}, fmt.Errorf("filter %q not found", ref)
+	case 1:
+		return found[0], nil
+	default:
+		return database.GetFilterRulesForUserRow{}, fmt.Errorf("filter %q is ambiguous, type more characters of its ID", ref)
+	}
+}
+
+func handlerFilters(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 || cmd.args[0] != "apply" {
+		return errors.New("command 'filters' expects one subcommand: apply")
+	}
+
+	rules, err := s.db.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	userRules := make([]database.FilterRule, 0, len(rules))
+	for _, rule := range rules {
+		userRules = append(userRules, database.FilterRule{
+			ID:        rule.ID,
+			CreatedAt: rule.CreatedAt,
+			UpdatedAt: rule.UpdatedAt,
+			UserID:    rule.UserID,
+			FeedID:    rule.FeedID,
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Action:    rule.Action,
+			Tag:       rule.Tag,
+		})
+	}
+	compiled := compileRules(userRules)
+
+	posts, err := s.db.GetFollowedPosts(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	affected := 0
+	for _, post := range posts {
+		matched, err := applyFilterRules(s, compiled, filteredPost{
+			ID:          post.ID,
+			FeedID:      post.FeedID,
+			Title:       post.Title,
+			Description: post.Description,
+			Content:     post.Content.String,
+			Author:      post.Author,
+			Categories:  post.Categories,
+		})
+		if err != nil {
+			return err
+		}
+		if matched > 0 {
+			affected++
+		}
+	}
+
+	fmt.Printf("Filters matched %d of %d posts\n", affected, len(posts))
+	return nil
+}
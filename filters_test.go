@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestCompiledRuleMatches(t *testing.T) {
+	feedID, otherFeedID := uuid.New(), uuid.New()
+	post := filteredPost{
+		FeedID:      feedID,
+		Title:       "Go 1.24 Released",
+		Description: "<p>The Go team is happy to announce</p>",
+		Content:     "Full article about generics",
+		Author:      "Gopher Team",
+		Categories:  []string{"Programming", "Go"},
+	}
+
+	tests := []struct {
+		name  string
+		rule  database.FilterRule
+		match bool
+	}{
+		{"title keyword ignores case", database.FilterRule{Field: "title", MatchType: "keyword", Pattern: "RELEASED"}, true},
+		{"title keyword misses", database.FilterRule{Field: "title", MatchType: "keyword", Pattern: "rust"}, false},
+		{"title regex", database.FilterRule{Field: "title", MatchType: "regex", Pattern: `^Go \d+\.\d+`}, true},
+		{"regex is case sensitive", database.FilterRule{Field: "title", MatchType: "regex", Pattern: `released`}, false},
+		{"content matches description", database.FilterRule{Field: "content", MatchType: "keyword", Pattern: "happy"}, true},
+		{"content matches full content", database.FilterRule{Field: "content", MatchType: "keyword", Pattern: "generics"}, true},
+		{"content doesn't match title", database.FilterRule{Field: "content", MatchType: "keyword", Pattern: "released"}, false},
+		{"author keyword", database.FilterRule{Field: "author", MatchType: "keyword", Pattern: "gopher"}, true},
+		{"author regex", database.FilterRule{Field: "author", MatchType: "regex", Pattern: `Team$`}, true},
+		{"category equals ignoring case", database.FilterRule{Field: "category", MatchType: "keyword", Pattern: "programming"}, true},
+		{"category keyword must be whole", database.FilterRule{Field: "category", MatchType: "keyword", Pattern: "Program"}, false},
+		{"category regex", database.FilterRule{Field: "category", MatchType: "regex", Pattern: `^Prog`}, true},
+		{"scoped to the post's feed", database.FilterRule{FeedID: uuid.NullUUID{UUID: feedID, Valid: true}, Field: "title", MatchType: "keyword", Pattern: "go"}, true},
+		{"scoped to another feed", database.FilterRule{FeedID: uuid.NullUUID{UUID: otherFeedID, Valid: true}, Field: "title", MatchType: "keyword", Pattern: "go"}, false},
+		{"unknown field", database.FilterRule{Field: "url", MatchType: "keyword", Pattern: "go"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := compileRules([]database.FilterRule{tt.rule})
+			if len(rules) != 1 {
+				t.Fatalf("compiled %d rules, want 1", len(rules))
+			}
+			if got := rules[0].matches(post); got != tt.match {
+				t.Errorf("matches = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestCompileRules(t *testing.T) {
+	rules := []database.FilterRule{
+		{ID: uuid.New(), Field: "title", MatchType: "regex", Pattern: `(unclosed`},
+		{ID: uuid.New(), Field: "title", MatchType: "keyword", Pattern: `(unclosed`},
+		{ID: uuid.New(), Field: "title", MatchType: "regex", Pattern: `go+`},
+	}
+
+	compiled := compileRules(rules)
+	if len(compiled) != 2 {
+		t.Fatalf("compiled %d rules, want the invalid regex skipped", len(compiled))
+	}
+	if compiled[0].ID != rules[1].ID || compiled[0].re != nil {
+		t.Errorf("keyword rule compiled to %+v, want it kept without a regex", compiled[0])
+	}
+	if compiled[1].ID != rules[2].ID || compiled[1].re == nil {
+		t.Errorf("regex rule compiled to %+v, want its regex", compiled[1])
+	}
+	if !compiled[0].matches(filteredPost{Title: "an (unclosed paren"}) {
+		t.Error("keyword patterns should match literally")
+	}
+}
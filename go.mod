@@ -5,4 +5,6 @@ go 1.24.1
 require (
 	github.com/google/uuid v1.6.0
 	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.38.0
+	golang.org/x/text v0.23.0
 )
@@ -0,0 +1,741 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/config"
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// openTestDB applies the schema migrations to a throwaway Postgres schema and returns
+// queries bound to it. Tests using it are skipped unless GATOR_TEST_DB_URL is set.
+func openTestDB(t *testing.T) *database.Queries {
+	t.Helper()
+	return openTestState(t).db
+}
+
+// openTestState is openTestDB for code that needs a whole state, such as commands that
+// run in a transaction.
+func openTestState(t *testing.T) *state {
+	t.Helper()
+
+	dbURL := os.Getenv("GATOR_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("GATOR_TEST_DB_URL not set, skipping database test")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A single connection keeps the search_path below in effect for every query
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	schema := "gator_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := db.Exec(fmt.Sprintf("CREATE SCHEMA %s; SET search_path TO %s, public", schema, schema)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+	})
+
+	migrations, err := filepath.Glob(filepath.Join("sql", "schema", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(migrations)
+	for _, migration := range migrations {
+		contents, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(contents), "-- +goose Down")
+		if _, err := db.Exec(up); err != nil {
+			t.Fatalf("applying %s: %v", migration, err)
+		}
+	}
+
+	return &state{db: database.New(db), sqlDB: db, cfg: &config.Config{}}
+}
+
+func createTestUser(t *testing.T, db *database.Queries, name string) database.User {
+	t.Helper()
+	user, err := db.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func followTestFeed(t *testing.T, db *database.Queries, user database.User, feed database.Feed) {
+	t.Helper()
+	_, err := db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createTestFeed(t *testing.T, db *database.Queries, owner database.User, name, url string) database.Feed {
+	t.Helper()
+	feed, err := db.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+		Url:       url,
+		UserID:    owner.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func createTestPost(t *testing.T, db *database.Queries, feed database.Feed, title, url string) database.CreatePostRow {
+	t.Helper()
+	post, err := db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		PublishedAt: time.Now(),
+		Title:       title,
+		Url:         url,
+		FeedID:      feed.ID,
+		Categories:  []string{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+// createTestFilterRule gives user a rule that applies action to posts with "sponsored" in
+// their title.
+func createTestFilterRule(t *testing.T, db *database.Queries, user database.User, action string) database.FilterRule {
+	t.Helper()
+	rule, err := db.CreateFilterRule(context.Background(), database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Field:     "title",
+		MatchType: "keyword",
+		Pattern:   "sponsored",
+		Action:    action,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+func TestBrowseRejectsInvalidPaging(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+
+	for _, args := range [][]string{{"0"}, {"-3"}, {"--offset", "-1"}} {
+		if err := handlerBrowse(s, command{name: "browse", args: args}, alice); err == nil {
+			t.Errorf("browse %q succeeded", args)
+		}
+	}
+}
+
+func TestBrowseShowsPostsOfFollowedFeeds(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	carol := createTestUser(t, db, "carol")
+
+	feed := createTestFeed(t, db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+	followTestFeed(t, db, alice, feed)
+	followTestFeed(t, db, bob, feed)
+
+	post, err := db.CreatePost(ctx, database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		PublishedAt: time.Now(),
+		Title:       "Hello from Alice",
+		Url:         "https://alice.example.com/hello",
+		Description: "First post",
+		FeedID:      feed.ID,
+		Categories:  []string{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	browse := func(user database.User) []database.BrowsePostsRow {
+		posts, err := db.BrowsePosts(ctx, database.BrowsePostsParams{
+			UserID:    user.ID,
+			PostLimit: 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return posts
+	}
+
+	if posts := browse(bob); len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("follower bob got %+v, want the post of the feed alice added", posts)
+	}
+	if posts := browse(alice); len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("creator alice got %+v, want her own post", posts)
+	}
+	if posts := browse(carol); len(posts) != 0 {
+		t.Errorf("carol doesn't follow the feed but got %+v", posts)
+	}
+}
+
+// createBackupFixture fills s with a user following a feed with one post they have read,
+// starred and tagged, a filter rule and a webhook for the posts that rule stars.
+func createBackupFixture(t *testing.T, s *state) (database.User, database.Feed, database.Post) {
+	t.Helper()
+	ctx := context.Background()
+
+	alice := createTestUser(t, s.db, "alice")
+	feed := createTestFeed(t, s.db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+	followTestFeed(t, s.db, alice, feed)
+
+	created, err := s.db.CreatePost(ctx, database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		PublishedAt: time.Now(),
+		Title:       "Hello from Alice",
+		Url:         "https://alice.example.com/hello",
+		Description: "First post",
+		FeedID:      feed.ID,
+		Categories:  []string{"intro"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := s.db.GetPost(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: alice.ID, PostID: post.ID, ReadAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.StarPost(ctx, database.StarPostParams{UserID: alice.ID, PostID: post.ID, StarredAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.TagPost(ctx, database.TagPostParams{UserID: alice.ID, PostID: post.ID, Tag: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	rule := createTestFilterRule(t, s.db, alice, filterActionStar)
+	_, err = s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    alice.ID,
+		Url:       "https://hooks.example.com/gator",
+		Secret:    "s3cret",
+		FeedID:    uuid.NullUUID{UUID: feed.ID, Valid: true},
+		FilterID:  uuid.NullUUID{UUID: rule.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alice, feed, post
+}
+
+// restoreTestBackup restores the backup file at path into s.
+func restoreTestBackup(t *testing.T, s *state, path string) *restorer {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := restoreBackup(s, zr)
+	if err != nil {
+		t.Fatalf("restoreBackup: %v", err)
+	}
+	return r
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	src := openTestState(t)
+	dst := openTestState(t)
+	ctx := context.Background()
+	alice, feed, post := createBackupFixture(t, src)
+
+	path := filepath.Join(t.TempDir(), "gator.json.gz")
+	if err := handlerBackup(src, command{name: "backup", args: []string{path}}); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	r := restoreTestBackup(t, dst, path)
+
+	for _, section := range []string{"users", "feeds", "feed_follows", "posts", "post_reads", "post_stars", "post_tags", "filter_rules", "webhooks"} {
+		if r.added[section] != 1 || r.merged[section] != 0 {
+			t.Errorf("%s: %d added, %d already present, want 1 added", section, r.added[section], r.merged[section])
+		}
+	}
+
+	user, err := dst.db.GetUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != alice.ID {
+		t.Errorf("restored alice with ID %s, want the backed up %s", user.ID, alice.ID)
+	}
+	restoredFeed, err := dst.db.GetFeedByURL(ctx, feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredFeed.Name != feed.Name {
+		t.Errorf("restored feed %q, want %q", restoredFeed.Name, feed.Name)
+	}
+	restoredPost, err := dst.db.GetPostByURL(ctx, post.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredPost.Title != post.Title || !restoredPost.PublishedAt.Equal(post.PublishedAt) {
+		t.Errorf("restored post %q published %v, want %q published %v", restoredPost.Title, restoredPost.PublishedAt, post.Title, post.PublishedAt)
+	}
+
+	starred, err := dst.db.GetStarredPosts(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 1 || starred[0].ID != restoredPost.ID {
+		t.Errorf("starred posts = %+v, want the restored post", starred)
+	}
+	tags, err := dst.db.GetPostTags(ctx, database.GetPostTagsParams{UserID: user.ID, PostID: restoredPost.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0] != "hello" {
+		t.Errorf("post tags = %v, want [hello]", tags)
+	}
+
+	rules, err := dst.db.GetFilterRulesForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks, err := dst.db.GetWebhooksForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || len(hooks) != 1 {
+		t.Fatalf("restored %d filter rules and %d webhooks, want 1 of each", len(rules), len(hooks))
+	}
+	if hooks[0].FeedID.UUID != restoredFeed.ID || hooks[0].FilterID.UUID != rules[0].ID || hooks[0].Secret != "s3cret" {
+		t.Errorf("restored webhook %+v, want it on the restored feed and filter rule", hooks[0])
+	}
+}
+
+func TestRestoreTwiceMergesEverything(t *testing.T) {
+	src := openTestState(t)
+	dst := openTestState(t)
+	ctx := context.Background()
+	createBackupFixture(t, src)
+
+	path := filepath.Join(t.TempDir(), "gator.json.gz")
+	if err := handlerBackup(src, command{name: "backup", args: []string{path}}); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	restoreTestBackup(t, dst, path)
+	r := restoreTestBackup(t, dst, path)
+
+	for _, section := range []string{"users", "feeds", "feed_follows", "posts", "post_reads", "post_stars", "post_tags", "filter_rules", "webhooks"} {
+		if r.added[section] != 0 || r.merged[section] != 1 {
+			t.Errorf("%s: %d added, %d already present, want 1 already present", section, r.added[section], r.merged[section])
+		}
+	}
+
+	users, err := dst.db.GetUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 {
+		t.Errorf("got %d users after restoring twice, want 1", len(users))
+	}
+	feeds, err := dst.db.GetFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 {
+		t.Errorf("got %d feeds after restoring twice, want 1", len(feeds))
+	}
+}
+
+func TestWebhooksAddRejectsHooksThatNeverFire(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+	bob := createTestUser(t, s.db, "bob")
+	feed := createTestFeed(t, s.db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+	followTestFeed(t, s.db, alice, feed)
+
+	hide := createTestFilterRule(t, s.db, bob, filterActionHide)
+
+	add := func(args ...string) error {
+		return handlerWebhooksAdd(s, command{name: "webhooks add", args: append([]string{"https://hooks.example.com/gator"}, args...)}, bob)
+	}
+	if err := add("--feed", feed.Name); err == nil {
+		t.Error("added a webhook for a feed bob doesn't follow")
+	}
+	if err := add("--filter", hide.ID.String()); err == nil {
+		t.Error("added a webhook for a filter that hides the posts it matches")
+	}
+
+	followTestFeed(t, s.db, bob, feed)
+	if err := add("--feed", feed.Name); err != nil {
+		t.Errorf("adding a webhook for a followed feed: %v", err)
+	}
+}
+
+func TestPostShortIDsResolveWithinFollowedFeeds(t *testing.T) {
+	s := openTestState(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, s.db, "alice")
+	bob := createTestUser(t, s.db, "bob")
+	aliceFeed := createTestFeed(t, s.db, alice, "alice", "https://alice.example.com/feed.xml")
+	followTestFeed(t, s.db, alice, aliceFeed)
+	bobFeed := createTestFeed(t, s.db, bob, "bob", "https://bob.example.com/feed.xml")
+	followTestFeed(t, s.db, bob, bobFeed)
+
+	createPost := func(id string, feed database.Feed) {
+		_, err := s.db.CreatePost(ctx, database.CreatePostParams{
+			ID:          uuid.MustParse(id),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			PublishedAt: time.Now(),
+			Title:       id,
+			Url:         "https://example.com/" + id,
+			FeedID:      feed.ID,
+			Categories:  []string{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two of alice's posts share their first eight characters, and one of bob's shares
+	// the first four with them.
+	createPost("1a2b3c4d-1111-4000-8000-000000000001", aliceFeed)
+	createPost("1a2b3c4d-2222-4000-8000-000000000002", aliceFeed)
+	createPost("1a2b9999-3333-4000-8000-000000000003", bobFeed)
+
+	id, err := postShortID(s, alice, uuid.MustParse("1a2b3c4d-1111-4000-8000-000000000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "1a2b3c4d-1" {
+		t.Errorf("short ID = %q, want it lengthened to 1a2b3c4d-1", id)
+	}
+	post, err := resolvePost(s, alice, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.ID.String() != "1a2b3c4d-1111-4000-8000-000000000001" {
+		t.Errorf("%s resolved to %s", id, post.ID)
+	}
+
+	if _, err := resolvePost(s, alice, "1a2b3c4d"); err == nil {
+		t.Error("resolved a prefix shared by two of alice's posts")
+	}
+	post, err = resolvePost(s, bob, "1a2b")
+	if err != nil {
+		t.Fatalf("resolving bob's only post starting with 1a2b: %v", err)
+	}
+	if post.FeedID != bobFeed.ID {
+		t.Errorf("bob's 1a2b resolved to a post of feed %s", post.FeedID)
+	}
+
+	// A duplicate follow doesn't make bob's post ambiguous
+	followTestFeed(t, s.db, bob, bobFeed)
+	if _, err := resolvePost(s, bob, "1a2b"); err != nil {
+		t.Errorf("resolving 1a2b after following twice: %v", err)
+	}
+
+	// Neither the full ID nor the URL reaches posts of feeds bob doesn't follow, unless
+	// he starred them
+	for _, ref := range []string{"1a2b3c4d-1111-4000-8000-000000000001", "https://example.com/1a2b3c4d-1111-4000-8000-000000000001"} {
+		if _, err := resolvePost(s, bob, ref); err == nil {
+			t.Errorf("bob resolved %s of a feed he doesn't follow", ref)
+		}
+	}
+	_, err = s.db.StarPost(ctx, database.StarPostParams{UserID: bob.ID, PostID: uuid.MustParse("1a2b3c4d-1111-4000-8000-000000000001"), StarredAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolvePost(s, bob, "1a2b3c4d-1111-4000-8000-000000000001"); err != nil {
+		t.Errorf("resolving a post bob starred: %v", err)
+	}
+}
+
+func TestFindFilterRuleNeedsAnIDPrefix(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+	rule := createTestFilterRule(t, s.db, alice, filterActionHide)
+
+	for _, ref := range []string{"", rule.ID.String()[:minIDPrefixLength-1]} {
+		if _, err := findFilterRule(s, alice, ref); err == nil {
+			t.Errorf("findFilterRule(%q) found a rule", ref)
+		}
+	}
+	found, err := findFilterRule(s, alice, rule.ID.String()[:minIDPrefixLength])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != rule.ID {
+		t.Errorf("found rule %s, want %s", found.ID, rule.ID)
+	}
+}
+
+func TestIngestFeedCountsNewPostsOnly(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+	feed := createTestFeed(t, s.db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+
+	fetched := &RSSFeed{}
+	fetched.Channel.Item = []RSSItem{
+		{Title: "First", Link: "https://alice.example.com/first", PubDate: "Fri, 01 Mar 2024 10:00:00 +0000"},
+		{Title: "Second", Link: "https://alice.example.com/second", PubDate: "Sat, 02 Mar 2024 10:00:00 +0000"},
+	}
+	if stored, err := ingestFeed(s, feed, fetched); err != nil || stored != 2 {
+		t.Fatalf("first ingest stored %d posts (err %v), want 2", stored, err)
+	}
+
+	fetched.Channel.Item = append(fetched.Channel.Item, RSSItem{Title: "Third", Link: "https://alice.example.com/third", PubDate: "Sun, 03 Mar 2024 10:00:00 +0000"})
+	if stored, err := ingestFeed(s, feed, fetched); err != nil || stored != 1 {
+		t.Errorf("second ingest stored %d posts (err %v), want only the new one", stored, err)
+	}
+}
+
+func TestFindWebhookNeedsAnIDPrefix(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+	hook, err := s.db.CreateWebhook(context.Background(), database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    alice.ID,
+		Url:       "https://hooks.example.com/gator",
+		Secret:    "s3cret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"", hook.ID.String()[:minIDPrefixLength-1]} {
+		if _, err := findWebhook(s, alice, ref); err == nil {
+			t.Errorf("findWebhook(%q) found a webhook", ref)
+		}
+	}
+	found, err := findWebhook(s, alice, hook.ID.String()[:minIDPrefixLength])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != hook.ID {
+		t.Errorf("found webhook %s, want %s", found.ID, hook.ID)
+	}
+}
+
+// createMultilingualFixture gives alice an English and a German feed with one post each
+// about alligators.
+func createMultilingualFixture(t *testing.T, s *state) database.User {
+	t.Helper()
+	alice := createTestUser(t, s.db, "alice")
+	english := createTestFeed(t, s.db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+	german := createTestFeed(t, s.db, alice, "Alices Blog", "https://alice.example.de/feed.xml")
+	err := s.db.SetFeedLanguage(context.Background(), database.SetFeedLanguageParams{Language: "german", ID: german.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, feed := range []database.Feed{english, german} {
+		followTestFeed(t, s.db, alice, feed)
+	}
+	createTestPost(t, s.db, english, "Alligators love feeds", "https://alice.example.com/alligators")
+	createTestPost(t, s.db, german, "Alligatoren lieben Feeds", "https://alice.example.de/alligatoren")
+	return alice
+}
+
+func TestSearchPostsCoversEveryLanguage(t *testing.T) {
+	s := openTestState(t)
+	alice := createMultilingualFixture(t, s)
+
+	tests := []struct {
+		config string
+		want   int
+	}{
+		{"", 2},
+		{"english", 1},
+		{"german", 1},
+		{"french", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.config, func(t *testing.T) {
+			results, err := s.db.SearchPosts(context.Background(), database.SearchPostsParams{
+				Config:    sql.NullString{String: tt.config, Valid: tt.config != ""},
+				Query:     "feeds",
+				UserID:    alice.ID,
+				PostLimit: 10,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("got %d results, want %d: %+v", len(results), tt.want, results)
+			}
+		})
+	}
+}
+
+func TestSavedSearchesCoverEveryLanguage(t *testing.T) {
+	s := openTestState(t)
+	ctx := context.Background()
+	alice := createMultilingualFixture(t, s)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"everywhere", []string{"everywhere", "feeds"}, 2},
+		{"german", []string{"german", "feeds", "--lang", "german"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := handlerSaveSearch(s, command{name: "savesearch", args: tt.args}, alice); err != nil {
+				t.Fatal(err)
+			}
+			tsquery, config, err := savedSearchQuery(s, alice, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			unread, err := s.db.CountUnreadSearchPosts(ctx, database.CountUnreadSearchPostsParams{Config: config, Query: tsquery, UserID: alice.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unread != int64(tt.want) {
+				t.Errorf("%d unread posts, want %d", unread, tt.want)
+			}
+			posts, err := s.db.BrowsePosts(ctx, database.BrowsePostsParams{
+				UserID:       alice.ID,
+				IncludeRead:  true,
+				Tsquery:      sql.NullString{String: tsquery, Valid: true},
+				SearchConfig: config,
+				PostLimit:    10,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(posts) != tt.want {
+				t.Errorf("browsed %d posts, want %d", len(posts), tt.want)
+			}
+		})
+	}
+
+	tsquery, config, err := savedSearchQuery(s, alice, "everywhere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	marked, err := s.db.MarkSearchPostsRead(ctx, database.MarkSearchPostsReadParams{UserID: alice.ID, ReadAt: time.Now(), Config: config, Query: tsquery})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marked != 2 {
+		t.Errorf("marked %d posts read, want 2", marked)
+	}
+}
+
+func TestDigestListsPostsOfFeedsFollowedTwiceOnce(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+	feed := createTestFeed(t, s.db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+	followTestFeed(t, s.db, alice, feed)
+	followTestFeed(t, s.db, alice, feed)
+	createTestPost(t, s.db, feed, "Hello from Alice", "https://alice.example.com/hello")
+
+	posts, err := s.db.GetDigestPosts(context.Background(), database.GetDigestPostsParams{
+		UserID: alice.ID,
+		Since:  time.Now().Add(-time.Hour),
+		Until:  time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 {
+		t.Errorf("digest lists %d posts, want 1", len(posts))
+	}
+}
+
+func TestFullContentNeedsTheFeedsOwner(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+	bob := createTestUser(t, s.db, "bob")
+	feed := createTestFeed(t, s.db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+	followTestFeed(t, s.db, bob, feed)
+
+	cmd := command{name: "fullcontent", args: []string{feed.Url, "on"}}
+	if err := handlerFullContent(s, cmd, bob); err == nil {
+		t.Error("bob changed full content fetching of alice's feed")
+	}
+	if err := handlerFullContent(s, cmd, alice); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := s.db.GetFeedByURL(context.Background(), feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.FetchFullContent {
+		t.Error("full content fetching is still off")
+	}
+}
+
+func TestFeedLanguageNeedsTheFeedsOwner(t *testing.T) {
+	s := openTestState(t)
+	alice := createTestUser(t, s.db, "alice")
+	bob := createTestUser(t, s.db, "bob")
+	feed := createTestFeed(t, s.db, alice, "Alice's blog", "https://alice.example.com/feed.xml")
+	followTestFeed(t, s.db, bob, feed)
+
+	cmd := command{name: "feedlang", args: []string{feed.Url, "german"}}
+	if err := handlerFeedLanguage(s, cmd, bob); err == nil {
+		t.Error("bob changed the language of alice's feed")
+	}
+	if err := handlerFeedLanguage(s, cmd, alice); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := s.db.GetFeedByURL(context.Background(), feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Language != "german" {
+		t.Errorf("language = %q, want german", updated.Language)
+	}
+}
@@ -10,8 +10,20 @@ const (
 )
 
 type Config struct {
-	DbURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbURL           string      `json:"db_url"`
+	CurrentUserName string      `json:"current_user_name"`
+	SMTP            *SMTPConfig `json:"smtp,omitempty"`
+}
+
+// SMTPConfig is the server digests are mailed through. Username and Password are only
+// needed when the server requires authentication.
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	StartTLS bool   `json:"starttls"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
 }
 
 func getConfigPath() (string, error) {
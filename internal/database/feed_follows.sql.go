@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
@@ -22,10 +23,10 @@ WITH new_row AS (
         $4,
         $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, tags
 )
 SELECT
-    nr.id, nr.created_at, nr.updated_at, nr.user_id, nr.feed_id,
+    nr.id, nr.created_at, nr.updated_at, nr.user_id, nr.feed_id, nr.tags,
     feeds.name as feed_name,
     users.name as user_name
 FROM
@@ -48,6 +49,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Tags      []string
 	FeedName  string
 	UserName  string
 }
@@ -67,6 +69,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		pq.Array(&i.Tags),
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -90,14 +93,16 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
-const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
+const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many 
 SELECT 
     feed_follows.id, 
     feed_follows.user_id, 
     users.name AS user_name, 
     feed_follows.feed_id, 
     feeds.name AS feed_name,
-    feeds.url AS feed_url
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
+    feed_follows.tags
 FROM 
     feed_follows
 INNER JOIN 
@@ -106,15 +111,18 @@ INNER JOIN
     feeds ON feed_follows.feed_id = feeds.id
 WHERE 
     feed_follows.user_id = $1
+ORDER BY feeds.name
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID       uuid.UUID
-	UserID   uuid.UUID
-	UserName string
-	FeedID   uuid.UUID
-	FeedName string
-	FeedUrl  string
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	UserName    string
+	FeedID      uuid.UUID
+	FeedName    string
+	FeedUrl     string
+	FeedSiteUrl string
+	Tags        []string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -133,6 +141,8 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.FeedID,
 			&i.FeedName,
 			&i.FeedUrl,
+			&i.FeedSiteUrl,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -146,3 +156,59 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const tagFeedFollow = `-- name: TagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = array_append(tags, $1::text), updated_at = $2
+WHERE user_id = $3
+AND feed_id = $4
+AND NOT $1::text = ANY(tags)
+`
+
+type TagFeedFollowParams struct {
+	Tag       string
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) TagFeedFollow(ctx context.Context, arg TagFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, tagFeedFollow,
+		arg.Tag,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const untagFeedFollow = `-- name: UntagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = array_remove(tags, $1::text), updated_at = $2
+WHERE user_id = $3
+AND feed_id = $4
+AND $1::text = ANY(tags)
+`
+
+type UntagFeedFollowParams struct {
+	Tag       string
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) UntagFeedFollow(ctx context.Context, arg UntagFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagFeedFollow,
+		arg.Tag,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
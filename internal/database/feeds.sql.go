@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id
+RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_content, parse_warnings, language, site_url
 `
 
 type CreateFeedParams struct {
@@ -52,6 +53,10 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.FetchFullContent,
+		&i.ParseWarnings,
+		&i.Language,
+		&i.SiteUrl,
 	)
 	return i, err
 }
@@ -66,7 +71,7 @@ func (q *Queries) DeleteFeeds(ctx context.Context) error {
 }
 
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id FROM feeds WHERE name = $1
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_content, parse_warnings, language, site_url FROM feeds WHERE name = $1
 `
 
 func (q *Queries) GetFeed(ctx context.Context, name string) (Feed, error) {
@@ -80,12 +85,39 @@ func (q *Queries) GetFeed(ctx context.Context, name string) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.FetchFullContent,
+		&i.ParseWarnings,
+		&i.Language,
+		&i.SiteUrl,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_content, parse_warnings, language, site_url FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastFetchedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.FetchFullContent,
+		&i.ParseWarnings,
+		&i.Language,
+		&i.SiteUrl,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_content, parse_warnings, language, site_url FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
@@ -99,18 +131,23 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.FetchFullContent,
+		&i.ParseWarnings,
+		&i.Language,
+		&i.SiteUrl,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds
+SELECT name, url, user_id, parse_warnings FROM feeds
 `
 
 type GetFeedsRow struct {
-	Name   string
-	Url    string
-	UserID uuid.UUID
+	Name          string
+	Url           string
+	UserID        uuid.UUID
+	ParseWarnings sql.NullString
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -122,7 +159,12 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.UserID); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.ParseWarnings,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -137,7 +179,7 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id FROM feeds ORDER BY last_fetched_at NULLS FIRST
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_content, parse_warnings, language, site_url FROM feeds ORDER BY last_fetched_at NULLS FIRST
 `
 
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
@@ -151,6 +193,10 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.FetchFullContent,
+		&i.ParseWarnings,
+		&i.Language,
+		&i.SiteUrl,
 	)
 	return i, err
 }
@@ -166,3 +212,71 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    fetch_full_content = $2
+WHERE id = $1
+`
+
+type SetFeedFetchFullContentParams struct {
+	ID               uuid.UUID
+	FetchFullContent bool
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchFullContent, arg.ID, arg.FetchFullContent)
+	return err
+}
+
+const setFeedLanguage = `-- name: SetFeedLanguage :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    language = $1::regconfig
+WHERE id = $2
+`
+
+type SetFeedLanguageParams struct {
+	Language string
+	ID       uuid.UUID
+}
+
+func (q *Queries) SetFeedLanguage(ctx context.Context, arg SetFeedLanguageParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedLanguage, arg.Language, arg.ID)
+	return err
+}
+
+const setFeedParseWarnings = `-- name: SetFeedParseWarnings :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    parse_warnings = $2
+WHERE id = $1
+`
+
+type SetFeedParseWarningsParams struct {
+	ID            uuid.UUID
+	ParseWarnings sql.NullString
+}
+
+func (q *Queries) SetFeedParseWarnings(ctx context.Context, arg SetFeedParseWarningsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedParseWarnings, arg.ID, arg.ParseWarnings)
+	return err
+}
+
+const setFeedSiteURL = `-- name: SetFeedSiteURL :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    site_url = $2
+WHERE id = $1
+`
+
+type SetFeedSiteURLParams struct {
+	ID      uuid.UUID
+	SiteUrl string
+}
+
+func (q *Queries) SetFeedSiteURL(ctx context.Context, arg SetFeedSiteURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteURL, arg.ID, arg.SiteUrl)
+	return err
+}
@@ -12,13 +12,17 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
-	Name          string
-	Url           string
-	UserID        uuid.UUID
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	LastFetchedAt    sql.NullTime
+	Name             string
+	Url              string
+	UserID           uuid.UUID
+	FetchFullContent bool
+	ParseWarnings    sql.NullString
+	Language         string
+	SiteUrl          string
 }
 
 type FeedFollow struct {
@@ -27,22 +31,114 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Tags      []string
+}
+
+type FilterRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	Tag       string
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	PublishedAt time.Time
-	Title       string
-	Url         string
-	Description string
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	PublishedAt  time.Time
+	Title        string
+	Url          string
+	Description  string
+	FeedID       uuid.UUID
+	Content      sql.NullString
+	Author       string
+	Categories   []string
+	Language     string
+	SearchVector string
 }
 
-type User struct {
+type PostEnclosure struct {
+	ID       uuid.UUID
+	PostID   uuid.UUID
+	Url      string
+	MimeType string
+	Length   int64
+}
+
+type PostHide struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	HiddenAt time.Time
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type PostTag struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+type SavedSearch struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	UserID    uuid.UUID
 	Name      string
+	Query     string
+	Language  sql.NullString
+}
+
+type User struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Name           string
+	LastDigestAt   sql.NullTime
+	Email          string
+	DigestSchedule string
+	LastMailedAt   sql.NullTime
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	FeedID    uuid.NullUUID
+	Tag       string
+	FilterID  uuid.NullUUID
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	WebhookID      uuid.UUID
+	PostID         uuid.UUID
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastStatusCode int32
+	LastError      string
+	DeliveredAt    sql.NullTime
 }
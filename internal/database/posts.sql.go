@@ -7,14 +7,161 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const browsePosts = `-- name: BrowsePosts :many
+SELECT 
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.content,
+    posts.published_at,
+    feeds.id AS feed_id,
+    feeds.name AS feed_name
+FROM 
+    posts
+INNER JOIN
+    feeds ON posts.feed_id = feeds.id
+WHERE 
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $1
+    )
+AND (
+    $2::bool
+    OR NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = $1
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = $1
+)
+AND ($3::text IS NULL OR feeds.name = $3::text)
+AND (
+    $4::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $1
+        AND $4::text = ANY(feed_follows.tags)
+    )
+)
+AND ($5::timestamp IS NULL OR posts.published_at >= $5::timestamp)
+AND ($6::timestamp IS NULL OR posts.published_at < $6::timestamp)
+AND (
+    $7::text IS NULL
+    OR posts.id IN (
+        SELECT matches.id
+        FROM (
+            SELECT $8::regconfig AS language
+            WHERE $8::regconfig IS NOT NULL
+            UNION
+            SELECT feeds.language FROM feeds
+            WHERE $8::regconfig IS NULL
+        ) AS languages
+        INNER JOIN posts AS matches ON matches.language = languages.language
+            AND matches.search_vector @@ to_tsquery(languages.language, $7::text)
+    )
+)
+AND (
+    $9::text IS NULL
+    OR posts.title ILIKE '%' || $9::text || '%'
+    OR posts.description ILIKE '%' || $9::text || '%'
+    OR posts.content ILIKE '%' || $9::text || '%'
+)
+ORDER BY
+    CASE WHEN $10::bool THEN posts.published_at END DESC,
+    CASE WHEN NOT $10::bool THEN posts.published_at END ASC,
+    posts.id
+LIMIT $11
+OFFSET $12
+`
+
+type BrowsePostsParams struct {
+	UserID       uuid.UUID
+	IncludeRead  bool
+	FeedName     sql.NullString
+	Tag          sql.NullString
+	Since        sql.NullTime
+	Until        sql.NullTime
+	Tsquery      sql.NullString
+	SearchConfig sql.NullString
+	Search       sql.NullString
+	NewestFirst  bool
+	PostLimit    int32
+	PostOffset   int32
+}
+
+type BrowsePostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description string
+	Content     sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	FeedName    string
+}
+
+func (q *Queries) BrowsePosts(ctx context.Context, arg BrowsePostsParams) ([]BrowsePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePosts,
+		arg.UserID,
+		arg.IncludeRead,
+		arg.FeedName,
+		arg.Tag,
+		arg.Since,
+		arg.Until,
+		arg.Tsquery,
+		arg.SearchConfig,
+		arg.Search,
+		arg.NewestFirst,
+		arg.PostLimit,
+		arg.PostOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsRow
+	for rows.Next() {
+		var i BrowsePostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Content,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
 WITH NP AS (
-    INSERT INTO posts(id, created_at, updated_at, published_at, title, url, description, feed_id)
+    INSERT INTO posts(id, created_at, updated_at, published_at, title, url, description, feed_id, author, categories, language)
     VALUES(
         $1,
         $2,
@@ -23,12 +170,15 @@ WITH NP AS (
         $5,
         $6,
         $7,
-        $8
+        $8,
+        $9,
+        $10,
+        (SELECT language FROM feeds WHERE feeds.id = $8)
     )
-    RETURNING id, created_at, updated_at, published_at, title, url, description, feed_id
+    RETURNING id, created_at, updated_at, published_at, title, url, description, feed_id, content, author, categories, language, search_vector
 )
 SELECT 
-    np.id, np.created_at, np.updated_at, np.published_at, np.title, np.url, np.description, np.feed_id,
+    np.id, np.created_at, np.updated_at, np.published_at, np.title, np.url, np.description, np.feed_id, np.content, np.author, np.categories, np.language, np.search_vector,
     feeds.name as feed_name
 FROM 
     NP
@@ -44,18 +194,25 @@ type CreatePostParams struct {
 	Url         string
 	Description string
 	FeedID      uuid.UUID
+	Author      string
+	Categories  []string
 }
 
 type CreatePostRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	PublishedAt time.Time
-	Title       string
-	Url         string
-	Description string
-	FeedID      uuid.UUID
-	FeedName    string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	PublishedAt  time.Time
+	Title        string
+	Url          string
+	Description  string
+	FeedID       uuid.UUID
+	Content      sql.NullString
+	Author       string
+	Categories   []string
+	Language     string
+	SearchVector string
+	FeedName     string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (CreatePostRow, error) {
@@ -68,6 +225,8 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (CreateP
 		arg.Url,
 		arg.Description,
 		arg.FeedID,
+		arg.Author,
+		pq.Array(arg.Categories),
 	)
 	var i CreatePostRow
 	err := row.Scan(
@@ -79,70 +238,510 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (CreateP
 		&i.Url,
 		&i.Description,
 		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Language,
+		&i.SearchVector,
 		&i.FeedName,
 	)
 	return i, err
 }
 
-const getPostsByUser = `-- name: GetPostsByUser :many
-SELECT 
+const exportPosts = `-- name: ExportPosts :many
+SELECT
     posts.id,
     posts.title,
     posts.url,
     posts.description,
+    posts.content,
+    posts.author,
+    posts.categories,
     posts.published_at,
-    feeds.id AS feed_id,
+    posts.created_at,
     feeds.name AS feed_name,
-    users.id AS user_id,
-    users.name AS user_name
-FROM 
+    feeds.url AS feed_url,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = $1
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = $1
+    ) AS is_starred
+FROM
     posts
 INNER JOIN
     feeds ON posts.feed_id = feeds.id
-INNER JOIN 
-    users ON feeds.user_id = users.id
-WHERE 
-    users.id = $1
-ORDER BY posts.published_at
-LIMIT $2
+WHERE
+    (
+        $2::bool
+        OR EXISTS (
+            SELECT 1 FROM feed_follows
+            WHERE feed_follows.feed_id = posts.feed_id
+            AND feed_follows.user_id = $1
+        )
+    )
+AND (
+    NOT $2::bool
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = $1
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = $1
+)
+AND ($3::text IS NULL OR feeds.name = $3::text)
+AND (
+    $4::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $1
+        AND $4::text = ANY(feed_follows.tags)
+    )
+)
+AND ($5::timestamp IS NULL OR posts.published_at >= $5::timestamp)
+AND ($6::timestamp IS NULL OR posts.published_at < $6::timestamp)
+AND (
+    NOT $7::bool
+    OR NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = $1
+    )
+)
+AND (
+    $8::timestamp IS NULL
+    OR (posts.published_at, posts.id) > ($8::timestamp, $9::uuid)
+)
+ORDER BY posts.published_at, posts.id
+LIMIT $10
 `
 
-type GetPostsByUserParams struct {
-	ID    uuid.UUID
-	Limit int32
+type ExportPostsParams struct {
+	UserID           uuid.UUID
+	StarredOnly      bool
+	FeedName         sql.NullString
+	Tag              sql.NullString
+	Since            sql.NullTime
+	Until            sql.NullTime
+	UnreadOnly       bool
+	AfterPublishedAt sql.NullTime
+	AfterID          uuid.NullUUID
+	PostLimit        int32
 }
 
-type GetPostsByUserRow struct {
+type ExportPostsRow struct {
 	ID          uuid.UUID
 	Title       string
 	Url         string
 	Description string
+	Content     sql.NullString
+	Author      string
+	Categories  []string
 	PublishedAt time.Time
-	FeedID      uuid.UUID
+	CreatedAt   time.Time
 	FeedName    string
-	UserID      uuid.UUID
-	UserName    string
+	FeedUrl     string
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) ExportPosts(ctx context.Context, arg ExportPostsParams) ([]ExportPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, exportPosts,
+		arg.UserID,
+		arg.StarredOnly,
+		arg.FeedName,
+		arg.Tag,
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.AfterPublishedAt,
+		arg.AfterID,
+		arg.PostLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportPostsRow
+	for rows.Next() {
+		var i ExportPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Content,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.IsRead,
+			&i.IsStarred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDigestPosts = `-- name: GetDigestPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.content,
+    posts.author,
+    posts.published_at,
+    feeds.name AS feed_name,
+    -- A subquery rather than a join, so a feed followed twice doesn't list its posts twice
+    (
+        SELECT feed_follows.tags FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $1
+        ORDER BY feed_follows.created_at
+        LIMIT 1
+    )::text[] AS tags
+FROM
+    posts
+INNER JOIN
+    feeds ON posts.feed_id = feeds.id
+WHERE
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $1
+    )
+AND posts.created_at > $2::timestamp
+AND posts.created_at <= $3::timestamp
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = $1
+)
+ORDER BY feeds.name, posts.published_at DESC, posts.id
+`
+
+type GetDigestPostsParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+	Until  time.Time
 }
 
-func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]GetPostsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.ID, arg.Limit)
+type GetDigestPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description string
+	Content     sql.NullString
+	Author      string
+	PublishedAt time.Time
+	FeedName    string
+	Tags        []string
+}
+
+func (q *Queries) GetDigestPosts(ctx context.Context, arg GetDigestPostsParams) ([]GetDigestPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestPosts, arg.UserID, arg.Since, arg.Until)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetPostsByUserRow
+	var items []GetDigestPostsRow
 	for rows.Next() {
-		var i GetPostsByUserRow
+		var i GetDigestPostsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Title,
 			&i.Url,
 			&i.Description,
+			&i.Content,
+			&i.Author,
 			&i.PublishedAt,
+			&i.FeedName,
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFollowedPosts = `-- name: GetFollowedPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.published_at, posts.title, posts.url, posts.description, posts.feed_id, posts.content, posts.author, posts.categories, posts.language, posts.search_vector
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id
+    AND feed_follows.user_id = $1
+)
+ORDER BY posts.published_at
+`
+
+func (q *Queries) GetFollowedPosts(ctx context.Context, userID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedPosts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PublishedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
 			&i.FeedID,
+			&i.Content,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.Language,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, published_at, title, url, description, feed_id, content, author, categories, language, search_vector FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PublishedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Language,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, published_at, title, url, description, feed_id, content, author, categories, language, search_vector FROM posts WHERE url = $1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PublishedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Language,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const getPostsByIDPrefix = `-- name: GetPostsByIDPrefix :many
+SELECT id, created_at, updated_at, published_at, title, url, description, feed_id, content, author, categories, language, search_vector FROM posts
+WHERE (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $1
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = $1
+    )
+)
+AND posts.id >= $2::uuid
+AND posts.id <= $3::uuid
+ORDER BY posts.id
+LIMIT 2
+`
+
+type GetPostsByIDPrefixParams struct {
+	UserID uuid.UUID
+	IDLow  uuid.UUID
+	IDHigh uuid.UUID
+}
+
+func (q *Queries) GetPostsByIDPrefix(ctx context.Context, arg GetPostsByIDPrefixParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByIDPrefix, arg.UserID, arg.IDLow, arg.IDHigh)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PublishedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.FeedID,
+			&i.Content,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.Language,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimelinePosts = `-- name: GetTimelinePosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.author,
+    posts.categories,
+    posts.published_at,
+    feeds.name AS feed_name
+FROM
+    posts
+INNER JOIN
+    feeds ON posts.feed_id = feeds.id
+WHERE
+    (
+        $1::bool
+        OR EXISTS (
+            SELECT 1 FROM feed_follows
+            WHERE feed_follows.feed_id = posts.feed_id
+            AND feed_follows.user_id = $2
+        )
+    )
+AND (
+    NOT $1::bool
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = $2
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = $2
+)
+AND (
+    $3::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $2
+        AND $3::text = ANY(feed_follows.tags)
+    )
+)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $4
+`
+
+type GetTimelinePostsParams struct {
+	StarredOnly bool
+	UserID      uuid.UUID
+	Tag         sql.NullString
+	PostLimit   int32
+}
+
+type GetTimelinePostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description string
+	Author      string
+	Categories  []string
+	PublishedAt time.Time
+	FeedName    string
+}
+
+func (q *Queries) GetTimelinePosts(ctx context.Context, arg GetTimelinePostsParams) ([]GetTimelinePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTimelinePosts,
+		arg.StarredOnly,
+		arg.UserID,
+		arg.Tag,
+		arg.PostLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTimelinePostsRow
+	for rows.Next() {
+		var i GetTimelinePostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.PublishedAt,
 			&i.FeedName,
-			&i.UserID,
-			&i.UserName,
 		); err != nil {
 			return nil, err
 		}
@@ -156,3 +755,140 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const getUserPost = `-- name: GetUserPost :one
+SELECT id, created_at, updated_at, published_at, title, url, description, feed_id, content, author, categories, language, search_vector FROM posts
+WHERE posts.id = $1
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $2
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = $2
+    )
+)
+`
+
+type GetUserPostParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetUserPost(ctx context.Context, arg GetUserPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getUserPost, arg.ID, arg.UserID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PublishedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Language,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const getUserPostByURL = `-- name: GetUserPostByURL :one
+SELECT id, created_at, updated_at, published_at, title, url, description, feed_id, content, author, categories, language, search_vector FROM posts
+WHERE posts.url = $1
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $2
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = $2
+    )
+)
+`
+
+type GetUserPostByURLParams struct {
+	Url    string
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetUserPostByURL(ctx context.Context, arg GetUserPostByURLParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getUserPostByURL, arg.Url, arg.UserID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PublishedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Language,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const notifyNewPost = `-- name: NotifyNewPost :exec
+SELECT pg_notify(
+    $1::text,
+    json_build_object('feed_id', $2::uuid, 'post_id', $3::uuid)::text
+)
+`
+
+type NotifyNewPostParams struct {
+	Channel string
+	FeedID  uuid.UUID
+	PostID  uuid.UUID
+}
+
+func (q *Queries) NotifyNewPost(ctx context.Context, arg NotifyNewPostParams) error {
+	_, err := q.db.ExecContext(ctx, notifyNewPost, arg.Channel, arg.FeedID, arg.PostID)
+	return err
+}
+
+const updateFeedPostsLanguage = `-- name: UpdateFeedPostsLanguage :exec
+UPDATE posts
+SET language = $1::regconfig
+WHERE feed_id = $2
+`
+
+type UpdateFeedPostsLanguageParams struct {
+	Language string
+	FeedID   uuid.UUID
+}
+
+func (q *Queries) UpdateFeedPostsLanguage(ctx context.Context, arg UpdateFeedPostsLanguageParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedPostsLanguage, arg.Language, arg.FeedID)
+	return err
+}
+
+const updatePostContent = `-- name: UpdatePostContent :exec
+UPDATE posts
+SET updated_at = NOW(),
+    content = $2
+WHERE id = $1
+`
+
+type UpdatePostContentParams struct {
+	ID      uuid.UUID
+	Content sql.NullString
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContent, arg.ID, arg.Content)
+	return err
+}
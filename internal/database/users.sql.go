@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,7 +21,7 @@ VALUES (
     $3,
     $4
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, last_digest_at, email, digest_schedule, last_mailed_at
 `
 
 type CreateUserParams struct {
@@ -43,6 +44,10 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.LastDigestAt,
+		&i.Email,
+		&i.DigestSchedule,
+		&i.LastMailedAt,
 	)
 	return i, err
 }
@@ -57,7 +62,7 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users WHERE name = $1
+SELECT id, created_at, updated_at, name, last_digest_at, email, digest_schedule, last_mailed_at FROM users WHERE name = $1
 `
 
 func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
@@ -68,12 +73,16 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.LastDigestAt,
+		&i.Email,
+		&i.DigestSchedule,
+		&i.LastMailedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, name FROM users WHERE id = $1
+SELECT id, created_at, updated_at, name, last_digest_at, email, digest_schedule, last_mailed_at FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -84,6 +93,10 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.LastDigestAt,
+		&i.Email,
+		&i.DigestSchedule,
+		&i.LastMailedAt,
 	)
 	return i, err
 }
@@ -114,3 +127,95 @@ func (q *Queries) GetUsers(ctx context.Context) ([]string, error) {
 	}
 	return items, nil
 }
+
+const getUsersWithDigestEmail = `-- name: GetUsersWithDigestEmail :many
+SELECT id, created_at, updated_at, name, last_digest_at, email, digest_schedule, last_mailed_at FROM users
+WHERE email <> ''
+AND digest_schedule <> ''
+ORDER BY name
+`
+
+func (q *Queries) GetUsersWithDigestEmail(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersWithDigestEmail)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.LastDigestAt,
+			&i.Email,
+			&i.DigestSchedule,
+			&i.LastMailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserDigestEmail = `-- name: SetUserDigestEmail :exec
+UPDATE users
+SET updated_at = NOW(),
+    email = $2,
+    digest_schedule = $3
+WHERE id = $1
+`
+
+type SetUserDigestEmailParams struct {
+	ID             uuid.UUID
+	Email          string
+	DigestSchedule string
+}
+
+func (q *Queries) SetUserDigestEmail(ctx context.Context, arg SetUserDigestEmailParams) error {
+	_, err := q.db.ExecContext(ctx, setUserDigestEmail, arg.ID, arg.Email, arg.DigestSchedule)
+	return err
+}
+
+const setUserLastDigest = `-- name: SetUserLastDigest :exec
+UPDATE users
+SET updated_at = NOW(),
+    last_digest_at = $2
+WHERE id = $1
+`
+
+type SetUserLastDigestParams struct {
+	ID           uuid.UUID
+	LastDigestAt sql.NullTime
+}
+
+func (q *Queries) SetUserLastDigest(ctx context.Context, arg SetUserLastDigestParams) error {
+	_, err := q.db.ExecContext(ctx, setUserLastDigest, arg.ID, arg.LastDigestAt)
+	return err
+}
+
+const setUserLastMailed = `-- name: SetUserLastMailed :exec
+UPDATE users
+SET updated_at = NOW(),
+    last_mailed_at = $2
+WHERE id = $1
+`
+
+type SetUserLastMailedParams struct {
+	ID           uuid.UUID
+	LastMailedAt sql.NullTime
+}
+
+func (q *Queries) SetUserLastMailed(ctx context.Context, arg SetUserLastMailedParams) error {
+	_, err := q.db.ExecContext(ctx, setUserLastMailed, arg.ID, arg.LastMailedAt)
+	return err
+}
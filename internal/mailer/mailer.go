@@ -0,0 +1,171 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/config"
+)
+
+const defaultPort = 587
+
+// timeout bounds connecting to the server and the whole conversation with it, so a server
+// that stops responding can't stall the caller.
+var timeout = time.Minute
+
+// Message is an email with a plain text and an HTML version of the same body.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Bytes encodes m as a multipart/alternative message, plain text first so that mail
+// clients prefer the HTML version.
+func (m Message) Bytes() ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", m.From},
+		{"To", m.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID(m.From)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", header[0], header[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// Send delivers msg through the server in cfg. With StartTLS set the connection is
+// upgraded before authenticating, and sending fails if the server can't do that.
+func Send(cfg config.SMTPConfig, msg Message) error {
+	if cfg.Host == "" {
+		return errors.New("no SMTP host configured")
+	}
+	if msg.From == "" {
+		msg.From = cfg.From
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if cfg.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s doesn't support STARTTLS", cfg.Host)
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(address(msg.From)); err != nil {
+		return err
+	}
+	if err := c.Rcpt(address(msg.To)); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// address returns the bare address of "Name <address>".
+func address(s string) string {
+	if start := strings.LastIndex(s, "<"); start >= 0 {
+		if end := strings.LastIndex(s, ">"); end > start {
+			return s[start+1 : end]
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+func messageID(from string) string {
+	domain := "gator.localhost"
+	if _, host, ok := strings.Cut(address(from), "@"); ok && host != "" {
+		domain = host
+	}
+	var b [12]byte
+	rand.Read(b[:])
+	return fmt.Sprintf("<%x.%d@%s>", b, time.Now().UnixNano(), domain)
+}
@@ -0,0 +1,252 @@
+package mailer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/config"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts one message per connection and
+// records what it was sent.
+type smtpStandIn struct {
+	listener   net.Listener
+	extensions []string
+	auth       chan string
+	from       chan string
+	to         chan string
+	data       chan string
+}
+
+func newSMTPStandIn(t *testing.T, extensions ...string) *smtpStandIn {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &smtpStandIn{
+		listener:   l,
+		extensions: extensions,
+		auth:       make(chan string, 1),
+		from:       make(chan string, 1),
+		to:         make(chan string, 1),
+		data:       make(chan string, 1),
+	}
+	t.Cleanup(func() { l.Close() })
+	go srv.serve()
+	return srv
+}
+
+func (srv *smtpStandIn) config() config.SMTPConfig {
+	addr := srv.listener.Addr().(*net.TCPAddr)
+	return config.SMTPConfig{
+		Host: addr.IP.String(),
+		Port: addr.Port,
+		From: "Gator <gator@example.com>",
+	}
+}
+
+func (srv *smtpStandIn) serve() {
+	conn, err := srv.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	reply := func(line string) {
+		tp.WriteString(line + "\r\n")
+		tp.Flush()
+	}
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := tp.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			lines := append([]string{"localhost"}, srv.extensions...)
+			for i, ext := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				reply("250" + sep + ext)
+			}
+		case "AUTH":
+			_, credentials, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			srv.auth <- string(decoded)
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			srv.from <- arg
+			reply("250 OK")
+		case "RCPT":
+			srv.to <- arg
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := tp.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			srv.data <- data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSendMultipartDigest(t *testing.T) {
+	srv := newSMTPStandIn(t, "AUTH PLAIN")
+	cfg := srv.config()
+	cfg.Username = "gator"
+	cfg.Password = "s3cret"
+
+	msg := Message{
+		To:      "Ann <ann@example.com>",
+		Subject: "Digest für Ann",
+		Text:    "1 new post\n\n* Café crème\n",
+		HTML:    `<p>1 new post</p><ul><li><a href="https://example.com/cafe">Café crème</a></li></ul>`,
+	}
+	if err := Send(cfg, msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if got := <-srv.auth; got != "\x00gator\x00s3cret" {
+		t.Errorf("auth = %q, want the configured credentials", got)
+	}
+	if got := <-srv.from; got != "FROM:<gator@example.com>" {
+		t.Errorf("MAIL = %q", got)
+	}
+	if got := <-srv.to; got != "TO:<ann@example.com>" {
+		t.Errorf("RCPT = %q", got)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(<-srv.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("subject = %q (%v), want %q", subject, err, msg.Subject)
+	}
+	if got := parsed.Header.Get("From"); got != cfg.From {
+		t.Errorf("from = %q, want %q", got, cfg.From)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q (%v), want multipart/alternative", mediaType, err)
+	}
+
+	// multipart.Reader undoes the quoted-printable encoding of each part
+	want := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for i, w := range want {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("part %d content type = %q, want %q", i, got, w.contentType)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Lines of a message end in CRLF once it has been sent
+		if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != w.body {
+			t.Errorf("part %d body = %q, want %q", i, got, w.body)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly two parts, got %v", err)
+	}
+}
+
+func TestSendRequiresStartTLS(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	cfg := srv.config()
+	cfg.StartTLS = true
+
+	err := Send(cfg, Message{To: "ann@example.com", Subject: "Digest", Text: "text", HTML: "<p>html</p>"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Send = %v, want an error about STARTTLS", err)
+	}
+}
+
+func TestSendTimesOutOnSilentServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	// Accept connections but never greet
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	defer func(saved time.Duration) { timeout = saved }(timeout)
+	timeout = 100 * time.Millisecond
+
+	addr := listener.Addr().(*net.TCPAddr)
+	cfg := config.SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "gator@example.com"}
+	start := time.Now()
+	err = Send(cfg, Message{To: "ann@example.com", Subject: "Digest", Text: "text", HTML: "<p>html</p>"})
+	if err == nil {
+		t.Fatal("Send succeeded against a server that never answered")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send gave up after %v, want about %v", elapsed, timeout)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"ann@example.com", "ann@example.com"},
+		{"Ann <ann@example.com>", "ann@example.com"},
+		{`"Smith, Ann" <ann@example.com>`, "ann@example.com"},
+		{" ann@example.com ", "ann@example.com"},
+	}
+	for _, tt := range tests {
+		if got := address(tt.in); got != tt.want {
+			t.Errorf("address(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
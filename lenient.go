@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+var (
+	cdataStart = []byte("<![CDATA[")
+	cdataEnd   = []byte("]]>")
+	entityRef  = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);`)
+	openTag    = regexp.MustCompile(`<([A-Za-z_][A-Za-z0-9_.:-]*)[^<>]*>\s*$`)
+)
+
+// feedAutoClose are the HTML elements that may be left unclosed in a lenient feed. It is
+// xml.HTMLAutoClose without link, which is a regular element with content in RSS.
+var feedAutoClose = slices.DeleteFunc(slices.Clone(xml.HTMLAutoClose), func(name string) bool {
+	return name == "link"
+})
+
+// decodeFeedLenient parses a feed that encoding/xml rejected in strict mode. The document is
+// pre-cleaned of the usual real-world breakage and decoded with HTML entities and auto-closing
+// tags allowed. It returns a warning for every repair it had to make.
+func decodeFeedLenient(rBody []byte, contentType string) (*RSSFeed, []string, error) {
+	var warnings []string
+	if charset, _ := externalCharset(rBody, contentType); charset != "utf-16le" && charset != "utf-16be" {
+		// The cleanup works on ASCII bytes, which UTF-16 doesn't keep intact
+		rBody, warnings = cleanFeed(rBody)
+	}
+
+	decoder, err := newFeedDecoder(rBody, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoder.Strict = false
+	decoder.AutoClose = feedAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var rFeed RSSFeed
+	err = decoder.Decode(&rFeed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &rFeed, warnings, nil
+}
+
+// cleanFeed strips control characters, escapes stray ampersands and closes unterminated
+// CDATA sections, leaving the content of CDATA sections untouched.
+func cleanFeed(body []byte) ([]byte, []string) {
+	var out bytes.Buffer
+	out.Grow(len(body))
+
+	var controlChars, strayAmps, openCDATA int
+	for i := 0; i < len(body); {
+		if bytes.HasPrefix(body[i:], cdataStart) {
+			end := bytes.Index(body[i+len(cdataStart):], cdataEnd)
+			if end >= 0 {
+				end += i + len(cdataStart) + len(cdataEnd)
+				out.Write(body[i:end])
+				i = end
+				continue
+			}
+
+			openCDATA++
+			end = unclosedCDATAEnd(body, i)
+			out.Write(body[i:end])
+			out.Write(cdataEnd)
+			i = end
+			continue
+		}
+
+		c := body[i]
+		switch {
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r':
+			controlChars++
+		case c == '&' && !entityRef.Match(body[i:]):
+			strayAmps++
+			out.WriteString("&amp;")
+		default:
+			out.WriteByte(c)
+		}
+		i++
+	}
+
+	var warnings []string
+	if controlChars > 0 {
+		warnings = append(warnings, fmt.Sprintf("removed %d control characters", controlChars))
+	}
+	if strayAmps > 0 {
+		warnings = append(warnings, fmt.Sprintf("escaped %d stray ampersands", strayAmps))
+	}
+	if openCDATA > 0 {
+		warnings = append(warnings, fmt.Sprintf("closed %d unterminated CDATA sections", openCDATA))
+	}
+	return out.Bytes(), warnings
+}
+
+// unclosedCDATAEnd guesses where the CDATA section starting at start should have ended:
+// right before the closing tag of the element it was opened in, or at the end of the document.
+func unclosedCDATAEnd(body []byte, start int) int {
+	match := openTag.FindSubmatch(body[:start])
+	if match == nil {
+		return len(body)
+	}
+	closing := []byte("</" + string(match[1]) + ">")
+	end := bytes.Index(body[start:], closing)
+	if end < 0 {
+		return len(body)
+	}
+	return start + end
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCleanFeed(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		warnings []string
+		item     string
+		link     string
+	}{
+		{"bare-ampersands.xml", []string{"escaped 6 stray ampersands"}, "Q&A: R&D at AT&T", "https://example.com/qa?id=1&page=2"},
+		{"unclosed-cdata.xml", []string{"closed 1 unterminated CDATA sections"}, "First", "https://example.com/1"},
+		{"control-chars.xml", []string{"removed 4 control characters"}, "Formfeed", "https://example.com/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", "lenient", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", "lenient", strings.TrimSuffix(tt.fixture, ".xml")+".golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cleaned, warnings := cleanFeed(body)
+			if string(cleaned) != string(want) {
+				t.Errorf("cleaned feed:\n%s\nwant:\n%s", cleaned, want)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+
+			feed, warnings, err := decodeFeedLenient(body, "application/rss+xml")
+			if err != nil {
+				t.Fatalf("decodeFeedLenient: %v", err)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("decodeFeedLenient warnings = %q, want %q", warnings, tt.warnings)
+			}
+			if len(feed.Channel.Item) != 1 || feed.Channel.Item[0].Title != tt.item || feed.Channel.Item[0].Link != tt.link {
+				t.Errorf("items = %+v, want one titled %q linking to %q", feed.Channel.Item, tt.item, tt.link)
+			}
+		})
+	}
+}
+
+func TestCleanFeedReportsEveryRepair(t *testing.T) {
+	body := "<rss><channel><title>A & B\x01</title><description><![CDATA[open</description></channel></rss>"
+	want := "<rss><channel><title>A &amp; B</title><description><![CDATA[open]]></description></channel></rss>"
+
+	cleaned, warnings := cleanFeed([]byte(body))
+	if string(cleaned) != want {
+		t.Errorf("cleaned = %q, want %q", cleaned, want)
+	}
+	wantWarnings := []string{"removed 1 control characters", "escaped 1 stray ampersands", "closed 1 unterminated CDATA sections"}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+
+	cleaned, warnings = cleanFeed([]byte(want))
+	if string(cleaned) != want || warnings != nil {
+		t.Errorf("cleaning a well-formed feed changed it to %q with warnings %q", cleaned, warnings)
+	}
+}
+
+func TestUnclosedCDATAEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"before the closing tag", "<description><![CDATA[text</description></item>", "<description><![CDATA[text"},
+		{"opening tag with attributes", `<content:encoded type="html"> <![CDATA[<p>x</p></content:encoded>`, `<content:encoded type="html"> <![CDATA[<p>x</p>`},
+		{"closing tag missing", "<description><![CDATA[text</item></rss>", "<description><![CDATA[text</item></rss>"},
+		{"not in an element", "<![CDATA[text</description>", "<![CDATA[text</description>"},
+		{"text before the section", "<title>Intro <![CDATA[text</title>", "<title>Intro <![CDATA[text</title>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := strings.Index(tt.body, "<![CDATA[")
+			if got := tt.body[:unclosedCDATAEnd([]byte(tt.body), start)]; got != tt.want {
+				t.Errorf("section ends after %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
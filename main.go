@@ -21,20 +21,44 @@ func main() {
 		log.Fatalln("can't open db")
 	}
 	dbQueries := database.New(db)
-	s := state{db: dbQueries, cfg: &userConfig}
+	s := state{db: dbQueries, sqlDB: db, cfg: &userConfig}
 
 	cmds := commands{cmd: make(map[string]func(*state, command) error)}
 	cmds.register("login", handlerLogin)
 	cmds.register("register", handlerRegister)
 	cmds.register("reset", handlerReset)
+	cmds.register("backup", handlerBackup)
+	cmds.register("restore", handlerRestore)
 	cmds.register("users", handlerUsers)
 	cmds.register("agg", handlerAgg)
+	cmds.register("watch", handlerWatch)
 	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	cmds.register("feeds", handlerFeeds)
+	cmds.register("fullcontent", middlewareLoggedIn(handlerFullContent))
+	cmds.register("feedlang", middlewareLoggedIn(handlerFeedLanguage))
 	cmds.register("follow", middlewareLoggedIn(handlerFollow))
 	cmds.register("following", middlewareLoggedIn(handlerFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
+	cmds.register("tag", middlewareLoggedIn(handlerTag))
+	cmds.register("untag", middlewareLoggedIn(handlerUntag))
+	cmds.register("import", middlewareLoggedIn(handlerImport))
+	cmds.register("export", middlewareLoggedIn(handlerExport))
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("show", middlewareLoggedIn(handlerShow))
+	cmds.register("search", middlewareLoggedIn(handlerSearch))
+	cmds.register("savesearch", middlewareLoggedIn(handlerSaveSearch))
+	cmds.register("searches", middlewareLoggedIn(handlerSavedSearches))
+	cmds.register("read", middlewareLoggedIn(handlerRead))
+	cmds.register("unread", middlewareLoggedIn(handlerUnread))
+	cmds.register("markread", middlewareLoggedIn(handlerMarkRead))
+	cmds.register("star", middlewareLoggedIn(handlerStar))
+	cmds.register("unstar", middlewareLoggedIn(handlerUnstar))
+	cmds.register("starred", middlewareLoggedIn(handlerStarred))
+	cmds.register("filter", middlewareLoggedIn(handlerFilter))
+	cmds.register("filters", middlewareLoggedIn(handlerFilters))
+	cmds.register("digest", middlewareLoggedIn(handlerDigest))
+	cmds.register("email", middlewareLoggedIn(handlerEmail))
+	cmds.register("webhooks", middlewareLoggedIn(handlerWebhooks))
 
 	args := os.Args[1:]
 	if len(args) == 0 {
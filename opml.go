@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Folders of nested outlines become tags whose parts are joined with this separator. A
+// separator or backslash in a folder's own title is escaped with a backslash, so the
+// folder isn't split when the tag is exported again.
+const opmlFolderSeparator = "/"
+
+var opmlFolderEscaper = strings.NewReplacer(`\`, `\\`, opmlFolderSeparator, `\`+opmlFolderSeparator)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type OPMLBody struct {
+	Outline []OPMLOutline `xml:"outline"`
+}
+
+type OPMLOutline struct {
+	Text    string        `xml:"text,attr"`
+	Title   string        `xml:"title,attr,omitempty"`
+	Type    string        `xml:"type,attr,omitempty"`
+	XMLURL  string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL string        `xml:"htmlUrl,attr,omitempty"`
+	Outline []OPMLOutline `xml:"outline"`
+}
+
+// opmlEntry is a feed listed in an OPML document, with the folders it was found in.
+type opmlEntry struct {
+	Name    string
+	URL     string
+	SiteURL string
+	Tags    []string
+}
+
+func parseOPML(body []byte) (*OPML, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charsetReader
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var doc OPML
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("couldn't parse OPML: %w", err)
+	}
+	return &doc, nil
+}
+
+// opmlEntries flattens the outlines of doc into feeds. A feed listed in several folders
+// is returned once with all of them as tags. Outlines that aren't usable feeds are
+// returned as descriptions of what is wrong with them.
+func opmlEntries(doc *OPML) ([]opmlEntry, []string) {
+	var entries []opmlEntry
+	var invalid []string
+	seen := make(map[string]int)
+
+	var visit func(outlines []OPMLOutline, folders []string)
+	visit = func(outlines []OPMLOutline, folders []string) {
+		for _, outline := range outlines {
+			name := strings.TrimSpace(outline.Title)
+			if name == "" {
+				name = strings.TrimSpace(outline.Text)
+			}
+
+			if outline.XMLURL == "" {
+				if len(outline.Outline) == 0 {
+					invalid = append(invalid, fmt.Sprintf("%q: no xmlUrl", name))
+					continue
+				}
+				folder := folders
+				if name != "" {
+					folder = append(folders[:len(folders):len(folders)], opmlFolderEscaper.Replace(name))
+				}
+				visit(outline.Outline, folder)
+				continue
+			}
+
+			feedURL := strings.TrimSpace(outline.XMLURL)
+			parsed, err := url.Parse(feedURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				invalid = append(invalid, fmt.Sprintf("%q: invalid xmlUrl %q", name, feedURL))
+				continue
+			}
+			if name == "" {
+				name = feedURL
+			}
+
+			i, ok := seen[feedURL]
+			if !ok {
+				i = len(entries)
+				seen[feedURL] = i
+				entries = append(entries, opmlEntry{Name: name, URL: feedURL, SiteURL: strings.TrimSpace(outline.HTMLURL)})
+			}
+			if len(folders) > 0 {
+				tag := strings.Join(folders, opmlFolderSeparator)
+				if !slices.Contains(entries[i].Tags, tag) {
+					entries[i].Tags = append(entries[i].Tags, tag)
+				}
+			}
+			// Feeds may nest outlines of their own; look for more feeds in them
+			visit(outline.Outline, folders)
+		}
+	}
+	visit(doc.Body.Outline, nil)
+
+	return entries, invalid
+}
+
+func handlerImport(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 || cmd.args[0] != "opml" {
+		return errors.New("command 'import' expects a format: opml <file> [--dry-run]")
+	}
+
+	sub := command{name: "import opml", args: cmd.args[1:]}
+	fs := newFlagSet(sub)
+	dryRun := fs.Bool("dry-run", false, "only print what would be imported")
+	args, err := parseFlags(fs, sub.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("command 'import opml' expects only one argument: <file>")
+	}
+
+	body, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	doc, err := parseOPML(body)
+	if err != nil {
+		return err
+	}
+	entries, invalid := opmlEntries(doc)
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing will be changed")
+		return importOPMLEntries(s, user, entries, invalid, true)
+	}
+	// Import the whole file or nothing, so a failure halfway can simply be retried
+	return inTx(s, nil, func(tx *state) error {
+		return importOPMLEntries(tx, user, entries, invalid, false)
+	})
+}
+
+// importOPMLEntries creates the feeds of entries gator doesn't know yet and follows and
+// tags all of them for user, printing what it does. With dryRun only the report is printed.
+func importOPMLEntries(s *state, user database.User, entries []opmlEntry, invalid []string, dryRun bool) error {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	followed := make(map[uuid.UUID]bool, len(follows))
+	for _, follow := range follows {
+		followed[follow.FeedID] = true
+	}
+
+	var created, followedCount, skipped int
+	takenNames := make(map[string]bool)
+	for _, entry := range entries {
+		feed, err := s.db.GetFeedByURL(context.Background(), entry.URL)
+		switch {
+		case err == nil:
+			if followed[feed.ID] {
+				skipped++
+				fmt.Printf("= %s (already followed)\n", feed.Name)
+			} else {
+				followedCount++
+				fmt.Printf("+ %s (followed)\n", feed.Name)
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			name, err := uniqueFeedName(s, entry.Name, takenNames)
+			if err != nil {
+				return err
+			}
+			takenNames[name] = true
+			created++
+			followedCount++
+			fmt.Printf("+ %s (created and followed)\n", name)
+
+			if dryRun {
+				continue
+			}
+			feed, err = s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+				Url:       entry.URL,
+				UserID:    user.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if entry.SiteURL != "" {
+				err = s.db.SetFeedSiteURL(context.Background(), database.SetFeedSiteURLParams{
+					ID:      feed.ID,
+					SiteUrl: entry.SiteURL,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			return err
+		}
+
+		if dryRun {
+			continue
+		}
+		if !followed[feed.ID] {
+			_, err = s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+			})
+			if err != nil {
+				return err
+			}
+			followed[feed.ID] = true
+		}
+		for _, tag := range entry.Tags {
+			_, err = s.db.TagFeedFollow(context.Background(), database.TagFeedFollowParams{
+				Tag:       tag,
+				UpdatedAt: time.Now(),
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, reason := range invalid {
+		fmt.Printf("! %s\n", reason)
+	}
+	fmt.Printf("Created %d, followed %d, skipped %d, invalid %d\n", created, followedCount, skipped, len(invalid))
+	return nil
+}
+
+// uniqueFeedName returns name, or name with a number appended when a feed with that name
+// already exists, since feed names must be unique.
+func uniqueFeedName(s *state, name string, taken map[string]bool) (string, error) {
+	candidate := name
+	for i := 2; ; i++ {
+		if !taken[candidate] {
+			_, err := s.db.GetFeed(context.Background(), candidate)
+			if errors.Is(err, sql.ErrNoRows) {
+				return candidate, nil
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+}
+
+// writeOPML writes the feeds the user follows as an OPML 2.0 document, with their tags as
+// folders. When tag is set, only feeds with that tag or a tag nested in it are written.
+func writeOPML(w io.Writer, user database.User, follows []database.GetFeedFollowsForUserRow, tag string) error {
+	doc := OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       fmt.Sprintf("gator subscriptions of %s", user.Name),
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+	}
+
+	var tagPath []string
+	if tag != "" {
+		tagPath = splitOPMLFolders(tag)
+	}
+	for _, follow := range follows {
+		outline := OPMLOutline{
+			Text:    follow.FeedName,
+			Title:   follow.FeedName,
+			Type:    "rss",
+			XMLURL:  follow.FeedUrl,
+			HTMLURL: follow.FeedSiteUrl,
+		}
+
+		var folders [][]string
+		for _, feedTag := range follow.Tags {
+			path := splitOPMLFolders(feedTag)
+			if len(path) >= len(tagPath) && slices.Equal(path[:len(tagPath)], tagPath) {
+				folders = append(folders, path)
+			}
+		}
+		if tag != "" && len(folders) == 0 {
+			continue
+		}
+		if len(folders) == 0 {
+			doc.Body.Outline = append(doc.Body.Outline, outline)
+			continue
+		}
+		for _, path := range folders {
+			addOPMLOutline(&doc.Body.Outline, path, outline)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// addOPMLOutline adds outline to the folder at path, creating the folders that don't exist yet.
+func addOPMLOutline(outlines *[]OPMLOutline, path []string, outline OPMLOutline) {
+	if len(path) == 0 {
+		*outlines = append(*outlines, outline)
+		return
+	}
+
+	for i := range *outlines {
+		folder := &(*outlines)[i]
+		if folder.XMLURL == "" && folder.Text == path[0] {
+			addOPMLOutline(&folder.Outline, path[1:], outline)
+			return
+		}
+	}
+	*outlines = append(*outlines, OPMLOutline{Text: path[0], Title: path[0]})
+	addOPMLOutline(&(*outlines)[len(*outlines)-1].Outline, path[1:], outline)
+}
+
+// splitOPMLFolders splits tag into the titles of the folders it is nested in, undoing
+// the escaping of separators in the titles.
+func splitOPMLFolders(tag string) []string {
+	var folders []string
+	var folder strings.Builder
+	escaped := false
+	for _, r := range tag {
+		switch {
+		case escaped:
+			folder.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case string(r) == opmlFolderSeparator:
+			folders = append(folders, folder.String())
+			folder.Reset()
+		default:
+			folder.WriteRune(r)
+		}
+	}
+	return append(folders, folder.String())
+}
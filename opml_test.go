@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/GLobyNew/gator/internal/database"
+)
+
+func TestOPMLRoundTrip(t *testing.T) {
+	follows := []database.GetFeedFollowsForUserRow{
+		{FeedName: "Go blog", FeedUrl: "https://go.dev/blog/feed.atom", FeedSiteUrl: "https://go.dev/blog", Tags: []string{"tech", "tech/go"}},
+		{FeedName: "Alice & Bob", FeedUrl: "https://alice.example.com/feed.xml?format=rss&full=1", FeedSiteUrl: "https://alice.example.com/"},
+		{FeedName: "Daily news", FeedUrl: "https://news.example.com/rss", Tags: []string{"news"}},
+		{FeedName: "Riffs", FeedUrl: "https://riffs.example.com/rss", Tags: []string{`music/AC\/DC`}},
+	}
+
+	tests := []struct {
+		name string
+		tag  string
+		want []opmlEntry
+	}{
+		{
+			name: "all feeds",
+			want: []opmlEntry{
+				{Name: "Alice & Bob", URL: "https://alice.example.com/feed.xml?format=rss&full=1", SiteURL: "https://alice.example.com/"},
+				{Name: "Daily news", URL: "https://news.example.com/rss", Tags: []string{"news"}},
+				{Name: "Go blog", URL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog", Tags: []string{"tech", "tech/go"}},
+				{Name: "Riffs", URL: "https://riffs.example.com/rss", Tags: []string{`music/AC\/DC`}},
+			},
+		},
+		{
+			name: "one tag",
+			tag:  "tech",
+			want: []opmlEntry{
+				{Name: "Go blog", URL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog", Tags: []string{"tech", "tech/go"}},
+			},
+		},
+		{
+			name: "nested tag",
+			tag:  "tech/go",
+			want: []opmlEntry{
+				{Name: "Go blog", URL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog", Tags: []string{"tech/go"}},
+			},
+		},
+		{
+			name: "tag with a separator in a folder title",
+			tag:  `music/AC\/DC`,
+			want: []opmlEntry{
+				{Name: "Riffs", URL: "https://riffs.example.com/rss", Tags: []string{`music/AC\/DC`}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeOPML(&buf, database.User{Name: "alice"}, follows, tt.tag); err != nil {
+				t.Fatalf("writeOPML: %v", err)
+			}
+
+			doc, err := parseOPML(buf.Bytes())
+			if err != nil {
+				t.Fatalf("parseOPML: %v\n%s", err, buf.String())
+			}
+			entries, invalid := opmlEntries(doc)
+			if len(invalid) != 0 {
+				t.Errorf("invalid entries: %v", invalid)
+			}
+
+			sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+			for i := range entries {
+				sort.Strings(entries[i].Tags)
+			}
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("entries = %+v\nwant %+v\nOPML:\n%s", entries, tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestOPMLFolderTitlesKeepSeparators(t *testing.T) {
+	doc, err := parseOPML([]byte(`<opml version="2.0"><body>
+<outline text="News/Tech"><outline text="Back\slash"><outline text="Example" xmlUrl="https://example.com/rss"/></outline></outline>
+</body></opml>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := opmlEntries(doc)
+	if want := `News\/Tech/Back\\slash`; len(entries) != 1 || !reflect.DeepEqual(entries[0].Tags, []string{want}) {
+		t.Fatalf("entries = %+v, want one tagged %q", entries, want)
+	}
+
+	if got, want := splitOPMLFolders(entries[0].Tags[0]), []string{"News/Tech", `Back\slash`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitOPMLFolders = %q, want %q", got, want)
+	}
+}
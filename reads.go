@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Length of the post handles printed by browse, long enough to rarely collide.
+const shortIDLength = 8
+
+// Shortest ID prefix accepted in place of a full post ID.
+const minIDPrefixLength = 4
+
+// shortID is the stable handle users type to refer to a post: a prefix of its UUID.
+func shortID(id uuid.UUID) string {
+	return id.String()[:shortIDLength]
+}
+
+// postShortID is shortID for a post the user can see, lengthened until no other post of
+// the user's followed feeds or starred posts shares it, so it always resolves to this post.
+func postShortID(s *state, user database.User, id uuid.UUID) (string, error) {
+	full := id.String()
+	for n := shortIDLength; n < len(full); n++ {
+		if full[n-1] == '-' {
+			continue
+		}
+		low, high, _ := idPrefixRange(full[:n])
+		posts, err := s.db.GetPostsByIDPrefix(context.Background(), database.GetPostsByIDPrefixParams{
+			UserID: user.ID,
+			IDLow:  low,
+			IDHigh: high,
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(posts) < 2 {
+			return full[:n], nil
+		}
+	}
+	return full, nil
+}
+
+// idPrefixRange returns the lowest and the highest ID starting with prefix. It reports
+// false if prefix isn't the start of a UUID written the way IDs are printed.
+func idPrefixRange(prefix string) (uuid.UUID, uuid.UUID, bool) {
+	const layout = "00000000-0000-0000-0000-000000000000"
+	if len(prefix) > len(layout) {
+		return uuid.Nil, uuid.Nil, false
+	}
+	for i := range len(prefix) {
+		if (prefix[i] == '-') != (layout[i] == '-') {
+			return uuid.Nil, uuid.Nil, false
+		}
+	}
+	low, err := uuid.Parse(prefix + layout[len(prefix):])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	high, err := uuid.Parse(prefix + strings.ReplaceAll(layout[len(prefix):], "0", "f"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return low, high, true
+}
+
+// resolvePost finds the post a command argument refers to: a full ID, an unambiguous
+// ID prefix as printed by browse, or the post's URL. Only posts of the user's followed
+// feeds and posts they starred are found.
+func resolvePost(s *state, user database.User, ref string) (database.Post, error) {
+	if id, err := uuid.Parse(ref); err == nil {
+		post, err := s.db.GetUserPost(context.Background(), database.GetUserPostParams{ID: id, UserID: user.ID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Post{}, fmt.Errorf("post %q not found", ref)
+		}
+		return post, err
+	}
+
+	prefix := strings.ToLower(ref)
+	if low, high, ok := idPrefixRange(prefix); ok && len(prefix) >= minIDPrefixLength {
+		posts, err := s.db.GetPostsByIDPrefix(context.Background(), database.GetPostsByIDPrefixParams{
+			UserID: user.ID,
+			IDLow:  low,
+			IDHigh: high,
+		})
+		if err != nil {
+			return database.Post{}, err
+		}
+		switch len(posts) {
+		case 1:
+			return posts[0], nil
+		case 2:
+			return database.Post{}, fmt.Errorf("post %q is ambiguous, type more characters of its ID", ref)
+		}
+	}
+
+	post, err := s.db.GetUserPostByURL(context.Background(), database.GetUserPostByURLParams{Url: ref, UserID: user.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Post{}, fmt.Errorf("post %q not found", ref)
+	}
+	return post, err
+}
+
+func handlerRead(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'read' expects only one argument: <post>")
+	}
+
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Marked %q as read\n", post.Title)
+	return nil
+}
+
+func handlerUnread(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'unread' expects only one argument: <post>")
+	}
+
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Marked %q as unread\n", post.Title)
+	return nil
+}
+
+func handlerMarkRead(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	feedName := fs.String("feed", "", "mark every post of the named feed as read")
+	before := fs.String("before", "", "mark every post published before this date as read")
+	searchName := fs.String("search-name", "", "mark every post matching the named saved search as read")
+	tag := fs.String("tag", "", "mark every post of the feeds with this tag as read")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	given := 0
+	for _, value := range []string{*feedName, *before, *searchName, *tag} {
+		if value != "" {
+			given++
+		}
+	}
+	if len(args) != 0 || given != 1 {
+		return errors.New("command 'markread' expects exactly one of: --feed <name> | --before <date> | --search-name <name> | --tag <tag>")
+	}
+
+	var marked int64
+	switch {
+	case *searchName != "":
+		tsquery, config, err := savedSearchQuery(s, user, *searchName)
+		if err != nil {
+			return err
+		}
+		marked, err = s.db.MarkSearchPostsRead(context.Background(), database.MarkSearchPostsReadParams{
+			UserID: user.ID,
+			ReadAt: time.Now(),
+			Config: config,
+			Query:  tsquery,
+		})
+		if err != nil {
+			return err
+		}
+	case *tag != "":
+		marked, err = s.db.MarkTagPostsRead(context.Background(), database.MarkTagPostsReadParams{
+			UserID: user.ID,
+			ReadAt: time.Now(),
+			Tag:    *tag,
+		})
+		if err != nil {
+			return err
+		}
+	case *feedName != "":
+		feed, err := s.db.GetFeed(context.Background(), *feedName)
+		if err != nil {
+			return err
+		}
+		marked, err = s.db.MarkFeedPostsRead(context.Background(), database.MarkFeedPostsReadParams{
+			UserID: user.ID,
+			ReadAt: time.Now(),
+			FeedID: feed.ID,
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		date, err := parseDate(*before)
+		if err != nil {
+			return err
+		}
+		marked, err = s.db.MarkPostsReadBefore(context.Background(), database.MarkPostsReadBeforeParams{
+			UserID:      user.ID,
+			ReadAt:      time.Now(),
+			PublishedAt: date,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Marked %d posts as read\n", marked)
+	return nil
+}
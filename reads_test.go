@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIDPrefixRange(t *testing.T) {
+	tests := []struct {
+		prefix    string
+		low, high string
+		ok        bool
+	}{
+		{"1a2b", "1a2b0000-0000-0000-0000-000000000000", "1a2bffff-ffff-ffff-ffff-ffffffffffff", true},
+		{"1a2b3c4d-5e", "1a2b3c4d-5e00-0000-0000-000000000000", "1a2b3c4d-5eff-ffff-ffff-ffffffffffff", true},
+		{"ffffffff", "ffffffff-0000-0000-0000-000000000000", "ffffffff-ffff-ffff-ffff-ffffffffffff", true},
+		{"1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d", "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d", "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d", true},
+		{"1a2b3c4d5e", "", "", false},
+		{"1a-2b", "", "", false},
+		{"xyz1", "", "", false},
+		{"1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d0", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			low, high, ok := idPrefixRange(tt.prefix)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if low != uuid.MustParse(tt.low) || high != uuid.MustParse(tt.high) {
+				t.Errorf("range = %s..%s, want %s..%s", low, high, tt.low, tt.high)
+			}
+		})
+	}
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type RSSFeed struct {
@@ -16,43 +20,167 @@ type RSSFeed struct {
 		Description string    `xml:"description"`
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
+	// Warnings lists the repairs made when the feed could only be parsed leniently
+	Warnings []string `xml:"-"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	Description string         `xml:"description"`
+	PubDate     string         `xml:"pubDate"`
+	Author      string         `xml:"author,omitempty"`
+	Creator     string         `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Category    []string       `xml:"category,omitempty"`
+	Enclosure   []RSSEnclosure `xml:"enclosure,omitempty"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
 }
 
 func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	rBody, contentType, err := fetchFeedBody(ctx, feedURL)
 	if err != nil {
 		return &RSSFeed{}, err
 	}
 
+	return parseFeed(rBody, contentType)
+}
+
+// fetchFeedBody downloads feedURL and returns the raw document with its Content-Type.
+func fetchFeedBody(ctx context.Context, feedURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
 	req.Header.Set("User-Agent", "gator")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return &RSSFeed{}, err
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status fetching %q: %s", feedURL, resp.Status)
 	}
 
 	rBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return rBody, resp.Header.Get("Content-Type"), nil
+}
+
+// validateFeed test-fetches feedURL and reports the detected format along with the parsed feed.
+func validateFeed(ctx context.Context, feedURL string) (*RSSFeed, string, error) {
+	rBody, contentType, err := fetchFeedBody(ctx, feedURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	format := detectFeedFormat(rBody, contentType)
+	rFeed, err := parseFeed(rBody, contentType)
+	if err != nil {
+		return nil, format, err
+	}
+	if len(rFeed.Channel.Item) == 0 {
+		return nil, format, errors.New("feed has no items")
+	}
+	for _, item := range rFeed.Channel.Item {
+		if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+			return nil, format, fmt.Errorf("item %q has an unsupported publication date: %w", item.Title, err)
+		}
+	}
+
+	return rFeed, format, nil
+}
+
+func parseFeed(rBody []byte, contentType string) (*RSSFeed, error) {
+	if format := detectFeedFormat(rBody, contentType); format != formatRSS2 {
+		return &RSSFeed{}, fmt.Errorf("unsupported feed format: %s", format)
+	}
+
+	decoder, err := newFeedDecoder(rBody, contentType)
 	if err != nil {
 		return &RSSFeed{}, err
 	}
 
 	var rFeed RSSFeed
-	err = xml.Unmarshal(rBody, &rFeed)
+	err = decoder.Decode(&rFeed)
 	if err != nil {
-		return &RSSFeed{}, err
+		lenientFeed, warnings, lenientErr := decodeFeedLenient(rBody, contentType)
+		if lenientErr != nil {
+			return &RSSFeed{}, err
+		}
+		rFeed = *lenientFeed
+		rFeed.Warnings = append([]string{fmt.Sprintf("strict parsing failed: %v", err)}, warnings...)
 	}
 	unescapeFeed(&rFeed)
 
 	return &rFeed, nil
 }
 
+const (
+	formatRSS2     = "RSS 2.0"
+	formatRSS1     = "RSS 1.0 (RDF)"
+	formatAtom     = "Atom"
+	formatJSONFeed = "JSON Feed"
+	formatHTML     = "HTML"
+	formatUnknown  = "unknown"
+)
+
+// detectFeedFormat names the kind of document in body by looking at its root element.
+func detectFeedFormat(body []byte, contentType string) string {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		if bytes.Contains(trimmed, []byte("jsonfeed.org/version")) {
+			return formatJSONFeed
+		}
+		return formatUnknown
+	}
+
+	decoder, err := newFeedDecoder(body, contentType)
+	if err != nil {
+		return formatUnknown
+	}
+	decoder.Strict = false
+	// Element names are ASCII, an unknown charset shouldn't hide the format
+	readCharset := decoder.CharsetReader
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if r, err := readCharset(label, input); err == nil {
+			return r, nil
+		}
+		return input, nil
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return formatUnknown
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(start.Name.Local) {
+		case "rss":
+			return formatRSS2
+		case "rdf":
+			return formatRSS1
+		case "feed":
+			return formatAtom
+		case "html":
+			return formatHTML
+		default:
+			return formatUnknown
+		}
+	}
+}
+
 func unescapeFeed(feed *RSSFeed) {
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// savedSearchQuery looks up the user's saved search by name and returns its tsquery and
+// configuration, which is null if the search covers every language.
+func savedSearchQuery(s *state, user database.User, name string) (string, sql.NullString, error) {
+	search, err := s.db.GetSavedSearch(context.Background(), database.GetSavedSearchParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", sql.NullString{}, fmt.Errorf("no saved search named %q", name)
+	}
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+
+	tsquery, err := buildTSQuery(search.Query)
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+	return tsquery, search.Language, nil
+}
+
+func handlerSaveSearch(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	lang := fs.String("lang", "", "only search posts indexed with this text search configuration")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 2 {
+		return errors.New("command 'savesearch' expects 2 args: <name> <query> [--lang <config>]")
+	}
+
+	name := args[0]
+	query := strings.Join(args[1:], " ")
+	if _, err := buildTSQuery(query); err != nil {
+		return err
+	}
+
+	search, err := s.db.CreateSavedSearch(context.Background(), database.CreateSavedSearchParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      name,
+		Query:     query,
+		Language:  sql.NullString{String: *lang, Valid: *lang != ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved search %q: %s\n", search.Name, search.Query)
+	return nil
+}
+
+func handlerSavedSearches(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("command 'searches' doesn't expect arguments")
+	}
+
+	searches, err := s.db.GetSavedSearches(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, search := range searches {
+		tsquery, err := buildTSQuery(search.Query)
+		if err != nil {
+			return err
+		}
+		unread, err := s.db.CountUnreadSearchPosts(context.Background(), database.CountUnreadSearchPostsParams{
+			Config: search.Language,
+			Query:  tsquery,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("* %s (%d unread) - %s\n", search.Name, unread, search.Query)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GLobyNew/gator/internal/database"
+)
+
+// Characters with a meaning in tsquery syntax, stripped from user-typed words.
+var tsqueryReplacer = strings.NewReplacer(
+	"&", " ", "|", " ", "!", " ", "(", " ", ")", " ", ":", " ",
+	"*", " ", "<", " ", ">", " ", "'", " ", "\"", " ", "\\", " ",
+)
+
+// buildTSQuery translates a search as users type it into to_tsquery syntax.
+// Words are ANDed together, "quoted words" must appear as a phrase, a trailing *
+// matches prefixes, OR between two terms matches either and -word excludes a word.
+func buildTSQuery(query string) (string, error) {
+	var terms []string
+	or := false
+	for query = strings.TrimSpace(query); query != ""; query = strings.TrimSpace(query) {
+		var term string
+		negate := false
+		if strings.HasPrefix(query, "-") {
+			negate = true
+			query = query[1:]
+		}
+
+		if strings.HasPrefix(query, `"`) {
+			phrase, rest, _ := strings.Cut(query[1:], `"`)
+			query = rest
+			var words []string
+			for _, word := range strings.Fields(phrase) {
+				if lexeme := tsqueryLexeme(word); lexeme != "" {
+					words = append(words, lexeme)
+				}
+			}
+			term = strings.Join(words, " <-> ")
+			if len(words) > 1 {
+				term = "(" + term + ")"
+			}
+		} else {
+			word, rest, _ := strings.Cut(query, " ")
+			query = rest
+			switch word {
+			case "OR":
+				or = len(terms) > 0
+				continue
+			case "AND":
+				continue
+			}
+			term = tsqueryLexeme(word)
+		}
+
+		if term == "" {
+			continue
+		}
+		if negate {
+			term = "!" + term
+		}
+		if or {
+			terms[len(terms)-1] = "(" + terms[len(terms)-1] + " | " + term + ")"
+			or = false
+			continue
+		}
+		terms = append(terms, term)
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("search query has no words to look for")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// tsqueryLexeme cleans a single word, turning a trailing * into a prefix match.
+func tsqueryLexeme(word string) string {
+	prefix := strings.HasSuffix(word, "*")
+	fields := strings.Fields(tsqueryReplacer.Replace(word))
+	if len(fields) == 0 {
+		return ""
+	}
+	lexeme := strings.Join(fields, " <-> ")
+	if len(fields) > 1 {
+		return "(" + lexeme + ")"
+	}
+	if prefix {
+		lexeme += ":*"
+	}
+	return lexeme
+}
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	feedName := fs.String("feed", "", "only search posts of the named feed")
+	since := fs.String("since", "", "only search posts published on or after this date")
+	until := fs.String("until", "", "only search posts published up to this date")
+	lang := fs.String("lang", "", "only search posts indexed with this text search configuration")
+	limit := fs.Int("limit", 10, "maximum number of results")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("invalid limit value: %d", *limit)
+	}
+
+	if len(args) == 0 {
+		return errors.New("command 'search' expects a query: <query> [--feed <name>] [--since <date>] [--until <date>] [--lang <config>] [--limit <n>]")
+	}
+
+	tsquery, err := buildTSQuery(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+
+	params := database.SearchPostsParams{
+		Config:    sql.NullString{String: *lang, Valid: *lang != ""},
+		Query:     tsquery,
+		UserID:    user.ID,
+		FeedName:  sql.NullString{String: *feedName, Valid: *feedName != ""},
+		PostLimit: int32(*limit),
+	}
+	params.Since, params.Until, err = parseDateRange(*since, *until)
+	if err != nil {
+		return err
+	}
+
+	results, err := s.db.SearchPosts(context.Background(), params)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No posts found")
+		return nil
+	}
+
+	for _, result := range results {
+		id, err := postShortID(s, user, result.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Println("--------------------------------------------------")
+		fmt.Printf("ID          : %s\n", id)
+		fmt.Printf("Title       : %s\n", result.Title)
+		fmt.Printf("Feed        : %s\n", result.FeedName)
+		fmt.Printf("Published At: %s\n", result.PublishedAt)
+		fmt.Printf("Rank        : %.3f\n", result.Rank)
+		fmt.Println(htmlToText(result.Snippet))
+		fmt.Println("--------------------------------------------------")
+	}
+	return nil
+}
+
+func handlerFeedLanguage(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return errors.New("command 'feedlang' expects 2 args: <feed url> <text search config> (e.g 'english', 'russian', 'simple')")
+	}
+
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.args[0])
+	if err != nil {
+		return err
+	}
+	// Every follower searches the feed's posts the same way
+	if feed.UserID != user.ID {
+		return fmt.Errorf("only the user who added %q can change its language", feed.Name)
+	}
+
+	err = s.db.SetFeedLanguage(context.Background(), database.SetFeedLanguageParams{
+		Language: cmd.args[1],
+		ID:       feed.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Existing posts get their search vector rebuilt with the new configuration
+	err = s.db.UpdateFeedPostsLanguage(context.Background(), database.UpdateFeedPostsLanguageParams{
+		Language: cmd.args[1],
+		FeedID:   feed.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Posts of %q are now searched as %q\n", feed.Name, cmd.args[1])
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"go rust", "go & rust", false},
+		{"  go   rust  ", "go & rust", false},
+		{"go AND rust", "go & rust", false},
+		{`"hello world" go`, "(hello <-> world) & go", false},
+		{`"single"`, "single", false},
+		{`"unterminated phrase`, "(unterminated <-> phrase)", false},
+		{"go OR rust", "(go | rust)", false},
+		{"go OR rust python", "(go | rust) & python", false},
+		{"OR go", "go", false},
+		{"go -java", "go & !java", false},
+		{"go OR -java", "(go | !java)", false},
+		{`-"hello world"`, "!(hello <-> world)", false},
+		{"gopher*", "gopher:*", false},
+		{"don't panic", "(don <-> t) & panic", false},
+		{"", "", true},
+		{"-", "", true},
+		{"&&& !! ()", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := buildTSQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("buildTSQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTSQueryLexeme(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"go", "go"},
+		{"go*", "go:*"},
+		{"c++", "c++"},
+		{"(x)", "x"},
+		{"a&b", "(a <-> b)"},
+		{"don't*", "(don <-> t)"},
+		{`back\slash`, "(back <-> slash)"},
+		{"***", ""},
+		{"!", ""},
+	}
+	for _, tt := range tests {
+		if got := tsqueryLexeme(tt.word); got != tt.want {
+			t.Errorf("tsqueryLexeme(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+)
+
+func handlerShow(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'show' expects only one argument: <post>")
+	}
+
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	feed, err := s.db.GetFeedByID(context.Background(), post.FeedID)
+	if err != nil {
+		return err
+	}
+
+	enclosures, err := s.db.GetPostEnclosures(context.Background(), post.ID)
+	if err != nil {
+		return err
+	}
+
+	tags, err := s.db.GetPostTags(context.Background(), database.GetPostTagsParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	id, err := postShortID(s, user, post.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("--------------------------------------------------")
+	fmt.Printf("ID          : %s\n", id)
+	fmt.Printf("Title       : %s\n", post.Title)
+	fmt.Printf("Link        : %s\n", post.Url)
+	if post.Author != "" {
+		fmt.Printf("Author      : %s\n", post.Author)
+	}
+	if len(post.Categories) > 0 {
+		fmt.Printf("Categories  : %s\n", strings.Join(post.Categories, ", "))
+	}
+	if len(tags) > 0 {
+		fmt.Printf("Tags        : %s\n", strings.Join(tags, ", "))
+	}
+	fmt.Printf("Feed        : %s (%s)\n", feed.Name, feed.Url)
+	fmt.Printf("Published At: %s\n", post.PublishedAt)
+	fmt.Printf("Fetched At  : %s\n", post.CreatedAt)
+	for _, enclosure := range enclosures {
+		fmt.Printf("Enclosure   : %s (%s, %d bytes)\n", enclosure.Url, enclosure.MimeType, enclosure.Length)
+	}
+	fmt.Println("--------------------------------------------------")
+	if post.Content.Valid {
+		fmt.Println(post.Content.String)
+	} else {
+		fmt.Println(htmlToText(post.Description))
+	}
+	fmt.Println("--------------------------------------------------")
+
+	_, err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: time.Now(),
+	})
+	return err
+}
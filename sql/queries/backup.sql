@@ -0,0 +1,47 @@
+-- name: BackupUsers :many
+SELECT * FROM users ORDER BY created_at;
+
+-- name: BackupFeeds :many
+SELECT * FROM feeds ORDER BY created_at;
+
+-- name: BackupFeedFollows :many
+SELECT * FROM feed_follows ORDER BY created_at;
+
+-- name: BackupPosts :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    published_at,
+    title,
+    url,
+    description,
+    feed_id,
+    content,
+    author,
+    categories
+FROM posts
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(post_limit);
+
+-- name: BackupPostReads :many
+SELECT * FROM post_reads;
+
+-- name: BackupPostStars :many
+SELECT * FROM post_stars;
+
+-- name: BackupPostHides :many
+SELECT * FROM post_hides;
+
+-- name: BackupPostTags :many
+SELECT * FROM post_tags;
+
+-- name: BackupFilterRules :many
+SELECT * FROM filter_rules ORDER BY created_at;
+
+-- name: BackupSavedSearches :many
+SELECT * FROM saved_searches ORDER BY created_at;
+
+-- name: BackupWebhooks :many
+SELECT * FROM webhooks ORDER BY created_at;
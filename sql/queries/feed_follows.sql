@@ -26,7 +26,9 @@ SELECT
     users.name AS user_name, 
     feed_follows.feed_id, 
     feeds.name AS feed_name,
-    feeds.url AS feed_url
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
+    feed_follows.tags
 FROM 
     feed_follows
 INNER JOIN 
@@ -34,10 +36,25 @@ INNER JOIN
 INNER JOIN 
     feeds ON feed_follows.feed_id = feeds.id
 WHERE 
-    feed_follows.user_id = $1;
+    feed_follows.user_id = $1
+ORDER BY feeds.name;
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
 USING users, feeds
 WHERE users.name = $1
-AND feeds.url = $2;
\ No newline at end of file
+AND feeds.url = $2;
+
+-- name: TagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = array_append(tags, sqlc.arg(tag)::text), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id)
+AND feed_id = sqlc.arg(feed_id)
+AND NOT sqlc.arg(tag)::text = ANY(tags);
+
+-- name: UntagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = array_remove(tags, sqlc.arg(tag)::text), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id)
+AND feed_id = sqlc.arg(feed_id)
+AND sqlc.arg(tag)::text = ANY(tags);
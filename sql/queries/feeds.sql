@@ -13,6 +13,9 @@ RETURNING *;
 -- name: GetFeed :one
 SELECT * FROM feeds WHERE name = $1;
 
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = $1;
+
 -- name: GetFeedByURL :one
 SELECT * FROM feeds WHERE url = $1;
 
@@ -20,7 +23,7 @@ SELECT * FROM feeds WHERE url = $1;
 DELETE FROM feeds;
 
 -- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds;
+SELECT name, url, user_id, parse_warnings FROM feeds;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds
@@ -29,4 +32,28 @@ SET updated_at = NOW(),
 WHERE id = $1;
 
 -- name: GetNextFeedToFetch :one
-SELECT * FROM feeds ORDER BY last_fetched_at NULLS FIRST;
\ No newline at end of file
+SELECT * FROM feeds ORDER BY last_fetched_at NULLS FIRST;
+
+-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    fetch_full_content = $2
+WHERE id = $1;
+
+-- name: SetFeedParseWarnings :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    parse_warnings = $2
+WHERE id = $1;
+
+-- name: SetFeedSiteURL :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    site_url = $2
+WHERE id = $1;
+
+-- name: SetFeedLanguage :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    language = sqlc.arg(language)::regconfig
+WHERE id = sqlc.arg(id);
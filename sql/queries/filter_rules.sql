@@ -0,0 +1,43 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, updated_at, user_id, feed_id, field, match_type, pattern, action, tag)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+RETURNING *;
+
+-- name: GetFilterRulesForUser :many
+SELECT
+    filter_rules.*,
+    feeds.name AS feed_name
+FROM
+    filter_rules
+LEFT JOIN
+    feeds ON filter_rules.feed_id = feeds.id
+WHERE
+    filter_rules.user_id = $1
+ORDER BY filter_rules.created_at;
+
+-- name: GetFilterRulesForFeed :many
+SELECT filter_rules.*
+FROM
+    filter_rules
+INNER JOIN
+    feed_follows ON feed_follows.user_id = filter_rules.user_id
+WHERE
+    feed_follows.feed_id = $1
+AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = $1)
+ORDER BY filter_rules.created_at;
+
+-- name: DeleteFilterRule :exec
+DELETE FROM filter_rules
+WHERE id = $1
+AND user_id = $2;
@@ -0,0 +1,12 @@
+-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (id, post_id, url, mime_type, length)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetPostEnclosures :many
+SELECT * FROM post_enclosures WHERE post_id = $1;
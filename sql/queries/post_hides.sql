@@ -0,0 +1,8 @@
+-- name: HidePost :execrows
+INSERT INTO post_hides (user_id, post_id, hidden_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
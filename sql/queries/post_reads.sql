@@ -0,0 +1,57 @@
+-- name: MarkPostRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1
+AND post_id = $2;
+
+-- name: MarkFeedPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT $1, posts.id, $2
+FROM posts
+WHERE posts.feed_id = $3
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT $1, posts.id, $2
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND posts.published_at < $3
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkSearchPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id), posts.id, sqlc.arg(read_at)
+FROM (
+    SELECT sqlc.narg(config)::regconfig AS language
+    WHERE sqlc.narg(config)::regconfig IS NOT NULL
+    UNION
+    SELECT feeds.language FROM feeds
+    WHERE sqlc.narg(config)::regconfig IS NULL
+) AS languages
+INNER JOIN posts ON posts.language = languages.language
+    AND posts.search_vector @@ to_tsquery(languages.language, sqlc.arg(query)::text)
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id
+    AND feed_follows.user_id = sqlc.arg(user_id)
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkTagPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id), posts.id, sqlc.arg(read_at)
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND sqlc.arg(tag)::text = ANY(feed_follows.tags)
+ON CONFLICT (user_id, post_id) DO NOTHING;
@@ -0,0 +1,14 @@
+-- name: TagPost :execrows
+INSERT INTO post_tags (user_id, post_id, tag)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: GetPostTags :many
+SELECT tag FROM post_tags
+WHERE user_id = $1
+AND post_id = $2
+ORDER BY tag;
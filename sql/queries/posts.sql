@@ -1,6 +1,6 @@
 -- name: CreatePost :one
 WITH NP AS (
-    INSERT INTO posts(id, created_at, updated_at, published_at, title, url, description, feed_id)
+    INSERT INTO posts(id, created_at, updated_at, published_at, title, url, description, feed_id, author, categories, language)
     VALUES(
         $1,
         $2,
@@ -9,7 +9,10 @@ WITH NP AS (
         $5,
         $6,
         $7,
-        $8
+        $8,
+        $9,
+        $10,
+        (SELECT language FROM feeds WHERE feeds.id = $8)
     )
     RETURNING *
 )
@@ -21,24 +24,319 @@ FROM
 INNER JOIN feeds ON NP.feed_id = feeds.id;
 
 
--- name: GetPostsByUser :many
+-- name: BrowsePosts :many
 SELECT 
     posts.id,
     posts.title,
     posts.url,
     posts.description,
+    posts.content,
     posts.published_at,
     feeds.id AS feed_id,
-    feeds.name AS feed_name,
-    users.id AS user_id,
-    users.name AS user_name
+    feeds.name AS feed_name
 FROM 
     posts
 INNER JOIN
     feeds ON posts.feed_id = feeds.id
-INNER JOIN 
-    users ON feeds.user_id = users.id
 WHERE 
-    users.id = $1
-ORDER BY posts.published_at
-LIMIT $2;
\ No newline at end of file
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+AND (
+    sqlc.arg(include_read)::bool
+    OR NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = sqlc.arg(user_id)
+)
+AND (sqlc.narg(feed_name)::text IS NULL OR feeds.name = sqlc.narg(feed_name)::text)
+AND (
+    sqlc.narg(tag)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+        AND sqlc.narg(tag)::text = ANY(feed_follows.tags)
+    )
+)
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.published_at < sqlc.narg(until)::timestamp)
+AND (
+    sqlc.narg(tsquery)::text IS NULL
+    OR posts.id IN (
+        SELECT matches.id
+        FROM (
+            SELECT sqlc.narg(search_config)::regconfig AS language
+            WHERE sqlc.narg(search_config)::regconfig IS NOT NULL
+            UNION
+            SELECT feeds.language FROM feeds
+            WHERE sqlc.narg(search_config)::regconfig IS NULL
+        ) AS languages
+        INNER JOIN posts AS matches ON matches.language = languages.language
+            AND matches.search_vector @@ to_tsquery(languages.language, sqlc.narg(tsquery)::text)
+    )
+)
+AND (
+    sqlc.narg(search)::text IS NULL
+    OR posts.title ILIKE '%' || sqlc.narg(search)::text || '%'
+    OR posts.description ILIKE '%' || sqlc.narg(search)::text || '%'
+    OR posts.content ILIKE '%' || sqlc.narg(search)::text || '%'
+)
+ORDER BY
+    CASE WHEN sqlc.arg(newest_first)::bool THEN posts.published_at END DESC,
+    CASE WHEN NOT sqlc.arg(newest_first)::bool THEN posts.published_at END ASC,
+    posts.id
+LIMIT sqlc.arg(post_limit)
+OFFSET sqlc.arg(post_offset);
+
+-- name: GetFollowedPosts :many
+SELECT posts.*
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id
+    AND feed_follows.user_id = $1
+)
+ORDER BY posts.published_at;
+
+-- name: GetPost :one
+SELECT * FROM posts WHERE id = $1;
+
+-- name: GetPostByURL :one
+SELECT * FROM posts WHERE url = $1;
+
+-- name: GetUserPost :one
+SELECT * FROM posts
+WHERE posts.id = sqlc.arg(id)
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    )
+);
+
+-- name: GetUserPostByURL :one
+SELECT * FROM posts
+WHERE posts.url = sqlc.arg(url)
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    )
+);
+
+-- name: GetPostsByIDPrefix :many
+SELECT * FROM posts
+WHERE (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    )
+)
+AND posts.id >= sqlc.arg(id_low)::uuid
+AND posts.id <= sqlc.arg(id_high)::uuid
+ORDER BY posts.id
+LIMIT 2;
+
+-- name: UpdateFeedPostsLanguage :exec
+UPDATE posts
+SET language = sqlc.arg(language)::regconfig
+WHERE feed_id = sqlc.arg(feed_id);
+
+-- name: UpdatePostContent :exec
+UPDATE posts
+SET updated_at = NOW(),
+    content = $2
+WHERE id = $1;
+-- name: ExportPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.content,
+    posts.author,
+    posts.categories,
+    posts.published_at,
+    posts.created_at,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    ) AS is_starred
+FROM
+    posts
+INNER JOIN
+    feeds ON posts.feed_id = feeds.id
+WHERE
+    (
+        sqlc.arg(starred_only)::bool
+        OR EXISTS (
+            SELECT 1 FROM feed_follows
+            WHERE feed_follows.feed_id = posts.feed_id
+            AND feed_follows.user_id = sqlc.arg(user_id)
+        )
+    )
+AND (
+    NOT sqlc.arg(starred_only)::bool
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = sqlc.arg(user_id)
+)
+AND (sqlc.narg(feed_name)::text IS NULL OR feeds.name = sqlc.narg(feed_name)::text)
+AND (
+    sqlc.narg(tag)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+        AND sqlc.narg(tag)::text = ANY(feed_follows.tags)
+    )
+)
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.published_at < sqlc.narg(until)::timestamp)
+AND (
+    NOT sqlc.arg(unread_only)::bool
+    OR NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    )
+)
+AND (
+    sqlc.narg(after_published_at)::timestamp IS NULL
+    OR (posts.published_at, posts.id) > (sqlc.narg(after_published_at)::timestamp, sqlc.narg(after_id)::uuid)
+)
+ORDER BY posts.published_at, posts.id
+LIMIT sqlc.arg(post_limit);
+
+-- name: GetTimelinePosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.author,
+    posts.categories,
+    posts.published_at,
+    feeds.name AS feed_name
+FROM
+    posts
+INNER JOIN
+    feeds ON posts.feed_id = feeds.id
+WHERE
+    (
+        sqlc.arg(starred_only)::bool
+        OR EXISTS (
+            SELECT 1 FROM feed_follows
+            WHERE feed_follows.feed_id = posts.feed_id
+            AND feed_follows.user_id = sqlc.arg(user_id)
+        )
+    )
+AND (
+    NOT sqlc.arg(starred_only)::bool
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    )
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = sqlc.arg(user_id)
+)
+AND (
+    sqlc.narg(tag)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+        AND sqlc.narg(tag)::text = ANY(feed_follows.tags)
+    )
+)
+ORDER BY posts.published_at DESC, posts.id
+LIMIT sqlc.arg(post_limit);
+
+-- name: GetDigestPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.content,
+    posts.author,
+    posts.published_at,
+    feeds.name AS feed_name,
+    -- A subquery rather than a join, so a feed followed twice doesn't list its posts twice
+    (
+        SELECT feed_follows.tags FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+        ORDER BY feed_follows.created_at
+        LIMIT 1
+    )::text[] AS tags
+FROM
+    posts
+INNER JOIN
+    feeds ON posts.feed_id = feeds.id
+WHERE
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+AND posts.created_at > sqlc.arg(since)::timestamp
+AND posts.created_at <= sqlc.arg(until)::timestamp
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = sqlc.arg(user_id)
+)
+ORDER BY feeds.name, posts.published_at DESC, posts.id;
+
+-- name: NotifyNewPost :exec
+SELECT pg_notify(
+    sqlc.arg(channel)::text,
+    json_build_object('feed_id', sqlc.arg(feed_id)::uuid, 'post_id', sqlc.arg(post_id)::uuid)::text
+);
@@ -0,0 +1,53 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, created_at, updated_at, user_id, name, query, language)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(updated_at),
+    sqlc.arg(user_id),
+    sqlc.arg(name),
+    sqlc.arg(query),
+    sqlc.narg(language)::regconfig
+)
+ON CONFLICT (user_id, name) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    query = EXCLUDED.query,
+    language = EXCLUDED.language
+RETURNING *;
+
+-- name: GetSavedSearch :one
+SELECT * FROM saved_searches
+WHERE user_id = $1
+AND name = $2;
+
+-- name: GetSavedSearches :many
+SELECT * FROM saved_searches
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: CountUnreadSearchPosts :one
+SELECT COUNT(*)
+FROM (
+    SELECT sqlc.narg(config)::regconfig AS language
+    WHERE sqlc.narg(config)::regconfig IS NOT NULL
+    UNION
+    SELECT feeds.language FROM feeds
+    WHERE sqlc.narg(config)::regconfig IS NULL
+) AS languages
+INNER JOIN posts ON posts.language = languages.language
+    AND posts.search_vector @@ to_tsquery(languages.language, sqlc.arg(query)::text)
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id
+    AND feed_follows.user_id = sqlc.arg(user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = sqlc.arg(user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = sqlc.arg(user_id)
+);
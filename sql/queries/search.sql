@@ -0,0 +1,44 @@
+-- name: SearchPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank(posts.search_vector, query)::real AS rank,
+    ts_headline(
+        posts.language,
+        coalesce(posts.content, posts.description),
+        query,
+        'MaxFragments=2, MinWords=8, MaxWords=24, StartSel=**, StopSel=**'
+    )::text AS snippet
+FROM (
+    -- One query per language the posts can be indexed in, so each is a constant the
+    -- search_vector index can be used with
+    SELECT sqlc.narg(config)::regconfig AS language
+    WHERE sqlc.narg(config)::regconfig IS NOT NULL
+    UNION
+    SELECT feeds.language FROM feeds
+    WHERE sqlc.narg(config)::regconfig IS NULL
+) AS languages
+CROSS JOIN LATERAL
+    to_tsquery(languages.language, sqlc.arg(query)::text) AS query
+INNER JOIN
+    posts ON posts.language = languages.language AND posts.search_vector @@ query
+INNER JOIN
+    feeds ON posts.feed_id = feeds.id
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id
+    AND feed_follows.user_id = sqlc.arg(user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = sqlc.arg(user_id)
+)
+AND (sqlc.narg(feed_name)::text IS NULL OR feeds.name = sqlc.narg(feed_name)::text)
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.published_at < sqlc.narg(until)::timestamp)
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT sqlc.arg(post_limit);
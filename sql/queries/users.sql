@@ -19,3 +19,28 @@ DELETE FROM users;
 
 -- name: GetUsers :many
 SELECT name FROM users;
+
+-- name: SetUserLastDigest :exec
+UPDATE users
+SET updated_at = NOW(),
+    last_digest_at = $2
+WHERE id = $1;
+
+-- name: SetUserLastMailed :exec
+UPDATE users
+SET updated_at = NOW(),
+    last_mailed_at = $2
+WHERE id = $1;
+
+-- name: SetUserDigestEmail :exec
+UPDATE users
+SET updated_at = NOW(),
+    email = $2,
+    digest_schedule = $3
+WHERE id = $1;
+
+-- name: GetUsersWithDigestEmail :many
+SELECT * FROM users
+WHERE email <> ''
+AND digest_schedule <> ''
+ORDER BY name;
@@ -0,0 +1,139 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, feed_id, tag, filter_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetWebhooksForUser :many
+SELECT
+    webhooks.*,
+    feeds.name AS feed_name,
+    (
+        SELECT COUNT(*) FROM webhook_deliveries
+        WHERE webhook_deliveries.webhook_id = webhooks.id
+        AND webhook_deliveries.status = 'pending'
+    ) AS pending,
+    (
+        SELECT COUNT(*) FROM webhook_deliveries
+        WHERE webhook_deliveries.webhook_id = webhooks.id
+        AND webhook_deliveries.status = 'failed'
+    ) AS failed
+FROM
+    webhooks
+LEFT JOIN
+    feeds ON webhooks.feed_id = feeds.id
+WHERE
+    webhooks.user_id = $1
+ORDER BY webhooks.created_at;
+
+-- name: GetWebhooksForFeed :many
+SELECT webhooks.*
+FROM
+    webhooks
+WHERE
+    (webhooks.feed_id IS NULL OR webhooks.feed_id = sqlc.arg(feed_id))
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.user_id = webhooks.user_id
+    AND feed_follows.feed_id = sqlc.arg(feed_id)
+    AND (webhooks.tag = '' OR webhooks.tag = ANY(feed_follows.tags))
+)
+ORDER BY webhooks.created_at;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1
+AND user_id = $2;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, post_id, payload, next_attempt_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (webhook_id, post_id) DO NOTHING;
+
+-- name: GetDueWebhookDeliveries :many
+SELECT
+    webhook_deliveries.id,
+    webhook_deliveries.payload,
+    webhook_deliveries.attempts,
+    webhooks.url,
+    webhooks.secret
+FROM
+    webhook_deliveries
+INNER JOIN
+    webhooks ON webhook_deliveries.webhook_id = webhooks.id
+WHERE
+    webhook_deliveries.status = 'pending'
+AND webhook_deliveries.next_attempt_at <= sqlc.arg(now)::timestamp
+ORDER BY webhook_deliveries.next_attempt_at
+LIMIT sqlc.arg(delivery_limit);
+
+-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE webhook_deliveries
+SET updated_at = NOW(),
+    status = 'delivered',
+    attempts = attempts + 1,
+    last_status_code = $2,
+    last_error = '',
+    delivered_at = $3
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET updated_at = NOW(),
+    status = sqlc.arg(status),
+    attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_status_code = sqlc.arg(last_status_code),
+    last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebhookDeliveries :many
+SELECT
+    webhook_deliveries.id,
+    webhook_deliveries.created_at,
+    webhook_deliveries.status,
+    webhook_deliveries.attempts,
+    webhook_deliveries.next_attempt_at,
+    webhook_deliveries.last_status_code,
+    webhook_deliveries.last_error,
+    webhook_deliveries.delivered_at,
+    webhooks.url AS webhook_url,
+    posts.title AS post_title
+FROM
+    webhook_deliveries
+INNER JOIN
+    webhooks ON webhook_deliveries.webhook_id = webhooks.id
+INNER JOIN
+    posts ON webhook_deliveries.post_id = posts.id
+WHERE
+    webhooks.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(webhook_id)::uuid IS NULL OR webhooks.id = sqlc.narg(webhook_id)::uuid)
+AND (sqlc.narg(status)::text IS NULL OR webhook_deliveries.status = sqlc.narg(status)::text)
+ORDER BY webhook_deliveries.created_at DESC, webhook_deliveries.id
+LIMIT sqlc.arg(delivery_limit);
+
+-- name: RetryWebhookDeliveries :execrows
+UPDATE webhook_deliveries
+SET updated_at = NOW(),
+    status = 'pending',
+    attempts = 0,
+    next_attempt_at = $2
+WHERE webhook_id = $1
+AND status = 'failed';
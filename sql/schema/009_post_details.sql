@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE post_enclosures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    length BIGINT NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE post_enclosures;
+ALTER TABLE posts DROP COLUMN categories;
+ALTER TABLE posts DROP COLUMN author;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN language REGCONFIG NOT NULL DEFAULT 'english';
+ALTER TABLE posts ADD COLUMN language REGCONFIG NOT NULL DEFAULT 'english';
+ALTER TABLE posts ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector(language, coalesce(title, '')), 'A') ||
+    setweight(to_tsvector(language, coalesce(description, '')), 'B') ||
+    setweight(to_tsvector(language, coalesce(content, '')), 'C')
+) STORED;
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_vector_idx;
+ALTER TABLE posts DROP COLUMN search_vector;
+ALTER TABLE posts DROP COLUMN language;
+ALTER TABLE feeds DROP COLUMN language;
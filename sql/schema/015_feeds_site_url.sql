@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN site_url TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN site_url;
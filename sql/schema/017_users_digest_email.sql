@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN digest_schedule TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN digest_schedule;
+ALTER TABLE users DROP COLUMN email;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN last_mailed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN last_mailed_at;
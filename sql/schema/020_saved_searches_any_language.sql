@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE saved_searches ALTER COLUMN language DROP NOT NULL;
+ALTER TABLE saved_searches ALTER COLUMN language DROP DEFAULT;
+
+-- +goose Down
+UPDATE saved_searches SET language = 'english' WHERE language IS NULL;
+ALTER TABLE saved_searches ALTER COLUMN language SET DEFAULT 'english';
+ALTER TABLE saved_searches ALTER COLUMN language SET NOT NULL;
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+)
+
+func handlerStar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'star' expects only one argument: <post>")
+	}
+
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.StarPost(context.Background(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		StarredAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Starred %q\n", post.Title)
+	return nil
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'unstar' expects only one argument: <post>")
+	}
+
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Unstarred %q\n", post.Title)
+	return nil
+}
+
+func handlerStarred(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("command 'starred' doesn't expect arguments")
+	}
+
+	posts, err := s.db.GetStarredPosts(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		id, err := postShortID(s, user, post.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Println("--------------------------------------------------")
+		fmt.Printf("ID          : %s\n", id)
+		fmt.Printf("Title       : %s\n", post.Title)
+		fmt.Printf("Description : %s\n", post.Description)
+		fmt.Printf("Published At: %s\n", post.PublishedAt)
+		fmt.Printf("Starred At  : %s\n", post.StarredAt)
+		fmt.Printf("Feed        : %s\n", post.FeedName)
+		fmt.Println("--------------------------------------------------")
+	}
+	return nil
+}
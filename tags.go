@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+)
+
+// followedFeed finds the feed the user follows by its name or URL.
+func followedFeed(s *state, user database.User, ref string) (database.GetFeedFollowsForUserRow, error) {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.GetFeedFollowsForUserRow{}, err
+	}
+	for _, follow := range follows {
+		if follow.FeedName == ref || follow.FeedUrl == ref {
+			return follow, nil
+		}
+	}
+	return database.GetFeedFollowsForUserRow{}, fmt.Errorf("you don't follow a feed named %q", ref)
+}
+
+func handlerTag(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return errors.New("command 'tag' expects two arguments: <feed name or url> <tag>")
+	}
+	tag := strings.TrimSpace(cmd.args[1])
+	if tag == "" {
+		return errors.New("tag can't be empty")
+	}
+
+	follow, err := followedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	tagged, err := s.db.TagFeedFollow(context.Background(), database.TagFeedFollowParams{
+		Tag:       tag,
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    follow.FeedID,
+	})
+	if err != nil {
+		return err
+	}
+	if tagged == 0 {
+		fmt.Printf("%s is already tagged %s\n", follow.FeedName, tag)
+		return nil
+	}
+
+	fmt.Printf("Tagged %s with %s\n", follow.FeedName, tag)
+	return nil
+}
+
+func handlerUntag(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return errors.New("command 'untag' expects two arguments: <feed name or url> <tag>")
+	}
+
+	follow, err := followedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	untagged, err := s.db.UntagFeedFollow(context.Background(), database.UntagFeedFollowParams{
+		Tag:       cmd.args[1],
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    follow.FeedID,
+	})
+	if err != nil {
+		return err
+	}
+	if untagged == 0 {
+		return fmt.Errorf("%s isn't tagged %s", follow.FeedName, cmd.args[1])
+	}
+
+	fmt.Printf("Removed tag %s from %s\n", cmd.args[1], follow.FeedName)
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	timelineFormatRSS  = "rss"
+	timelineFormatAtom = "atom"
+)
+
+// timelineOptions selects which of a user's posts a timeline feed carries and how it is written.
+type timelineOptions struct {
+	Format  string
+	Starred bool
+	Tag     string
+	Limit   int32
+	Link    string
+}
+
+// rssDocument wraps RSSFeed with the root element it needs when it is written out.
+type rssDocument struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	RSSFeed
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Link    []atomLink  `xml:"link"`
+	Entry   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Updated   string         `xml:"updated"`
+	Published string         `xml:"published"`
+	Author    *atomPerson    `xml:"author,omitempty"`
+	Link      []atomLink     `xml:"link"`
+	Category  []atomCategory `xml:"category"`
+	Summary   atomText       `xml:"summary"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func handlerExportFeed(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	format := fs.String("format", timelineFormatRSS, "feed format: rss|atom")
+	starred := fs.Bool("starred", false, "only include starred posts")
+	tag := fs.String("tag", "", "only include posts of feeds with this tag")
+	limit := fs.Int("limit", 50, "number of latest posts to include")
+	link := fs.String("link", "https://github.com/GLobyNew/gator", "link of the feed's channel")
+	output := fs.String("o", "", "file to write to instead of standard output")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("command 'export feed' expects flags only: [--format rss|atom] [--starred] [--tag <tag>] [--limit <n>] [--link <url>] [-o <file>]")
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("invalid limit value: %d", *limit)
+	}
+
+	opts := timelineOptions{
+		Format:  *format,
+		Starred: *starred,
+		Tag:     *tag,
+		Limit:   int32(*limit),
+		Link:    *link,
+	}
+	return writeExport(*output, func(w io.Writer) error {
+		return writeTimelineFeed(w, s, user, opts)
+	})
+}
+
+// writeTimelineFeed writes the latest posts of the user selected by opts as an RSS 2.0 or
+// Atom document.
+func writeTimelineFeed(w io.Writer, s *state, user database.User, opts timelineOptions) error {
+	if opts.Format != timelineFormatRSS && opts.Format != timelineFormatAtom {
+		return fmt.Errorf("invalid format %q, expected rss or atom", opts.Format)
+	}
+
+	feed, err := timelineFeed(s, user, opts)
+	if err != nil {
+		return err
+	}
+
+	// The ID stays the same between exports of the same selection, as Atom requires
+	id := uuid.NewSHA1(user.ID, []byte(fmt.Sprintf("starred=%t tag=%s", opts.Starred, opts.Tag)))
+	return encodeTimelineFeed(w, feed, opts.Format, "urn:uuid:"+id.String())
+}
+
+// encodeTimelineFeed writes feed as an RSS 2.0 document, or as an Atom document with the
+// given ID if format is timelineFormatAtom.
+func encodeTimelineFeed(w io.Writer, feed *RSSFeed, format, atomID string) error {
+	var doc any = rssDocument{Version: "2.0", RSSFeed: *feed}
+	if format == timelineFormatAtom {
+		doc = atomFromRSS(feed, atomID)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// timelineFeed collects the latest posts of the user selected by opts into an RSSFeed.
+func timelineFeed(s *state, user database.User, opts timelineOptions) (*RSSFeed, error) {
+	posts, err := s.db.GetTimelinePosts(context.Background(), database.GetTimelinePostsParams{
+		StarredOnly: opts.Starred,
+		UserID:      user.ID,
+		Tag:         sql.NullString{String: opts.Tag, Valid: opts.Tag != ""},
+		PostLimit:   opts.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	switch {
+	case opts.Starred:
+		feed.Channel.Title = fmt.Sprintf("%s's starred posts", user.Name)
+	case opts.Tag != "":
+		feed.Channel.Title = fmt.Sprintf("%s's %s feeds", user.Name, opts.Tag)
+	default:
+		feed.Channel.Title = fmt.Sprintf("%s's timeline", user.Name)
+	}
+	feed.Channel.Link = opts.Link
+	feed.Channel.Description = fmt.Sprintf("%s, collected by gator", feed.Channel.Title)
+
+	for _, post := range posts {
+		enclosures, err := s.db.GetPostEnclosures(context.Background(), post.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		item := RSSItem{
+			Title:       post.Title,
+			Link:        post.Url,
+			Description: post.Description,
+			PubDate:     post.PublishedAt.Format(time.RFC1123Z),
+			// RSS wants an email address in author, names go in dc:creator
+			Creator:  post.Author,
+			Category: append([]string{post.FeedName}, post.Categories...),
+		}
+		for _, enclosure := range enclosures {
+			item.Enclosure = append(item.Enclosure, RSSEnclosure{
+				URL:    enclosure.Url,
+				Type:   enclosure.MimeType,
+				Length: strconv.FormatInt(enclosure.Length, 10),
+			})
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
+
+func atomFromRSS(feed *RSSFeed, id string) atomFeed {
+	doc := atomFeed{
+		ID:     id,
+		Title:  feed.Channel.Title,
+		Author: atomPerson{Name: "gator"},
+		Link:   []atomLink{{Href: feed.Channel.Link}},
+	}
+
+	var updated time.Time
+	for _, item := range feed.Channel.Item {
+		published, _ := time.Parse(time.RFC1123Z, item.PubDate)
+		if published.After(updated) {
+			updated = published
+		}
+
+		entry := atomEntry{
+			ID:        item.Link,
+			Title:     item.Title,
+			Updated:   published.Format(time.RFC3339),
+			Published: published.Format(time.RFC3339),
+			Link:      []atomLink{{Href: item.Link, Rel: "alternate"}},
+			Summary:   atomText{Type: "html", Body: item.Description},
+		}
+		if item.Creator != "" {
+			entry.Author = &atomPerson{Name: item.Creator}
+		}
+		for _, category := range item.Category {
+			entry.Category = append(entry.Category, atomCategory{Term: category})
+		}
+		for _, enclosure := range item.Enclosure {
+			entry.Link = append(entry.Link, atomLink{
+				Href:   enclosure.URL,
+				Rel:    "enclosure",
+				Type:   enclosure.Type,
+				Length: enclosure.Length,
+			})
+		}
+		doc.Entry = append(doc.Entry, entry)
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	doc.Updated = updated.Format(time.RFC3339)
+
+	return doc
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testTimelineFeed() *RSSFeed {
+	feed := &RSSFeed{}
+	feed.Channel.Title = "alice's timeline"
+	feed.Channel.Link = "https://github.com/GLobyNew/gator"
+	feed.Channel.Description = "alice's timeline, collected by gator"
+	feed.Channel.Item = []RSSItem{
+		{
+			Title:       "Say \"hello\" & <wave>",
+			Link:        "https://alice.example.com/hello?lang=en&ref=gator",
+			Description: "<p>Hello, <b>world</b> &amp; friends</p>",
+			PubDate:     time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC).Format(time.RFC1123Z),
+			Creator:     "Alice",
+			Category:    []string{"Alice's blog", "greetings"},
+			Enclosure:   []RSSEnclosure{{URL: "https://alice.example.com/hello.mp3", Type: "audio/mpeg", Length: "1024"}},
+		},
+		{
+			Title:    "Older news",
+			Link:     "https://bob.example.com/news",
+			PubDate:  time.Date(2024, 2, 28, 8, 0, 0, 0, time.FixedZone("CET", 3600)).Format(time.RFC1123Z),
+			Category: []string{"Bob's news"},
+		},
+	}
+	return feed
+}
+
+func TestTimelineRSSParsesBack(t *testing.T) {
+	want := testTimelineFeed()
+	var buf bytes.Buffer
+	if err := encodeTimelineFeed(&buf, want, timelineFormatRSS, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc rssDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("xml.Unmarshal: %v\n%s", err, buf.String())
+	}
+	if doc.Version != "2.0" {
+		t.Errorf("version = %q, want 2.0", doc.Version)
+	}
+
+	parsed, err := parseFeed(buf.Bytes(), "application/rss+xml")
+	if err != nil {
+		t.Fatalf("parseFeed: %v\n%s", err, buf.String())
+	}
+	if len(parsed.Warnings) != 0 {
+		t.Errorf("parseFeed needed repairs: %q", parsed.Warnings)
+	}
+
+	for name, got := range map[string]*RSSFeed{"encoding/xml": &doc.RSSFeed, "parseFeed": parsed} {
+		t.Run(name, func(t *testing.T) {
+			if got.Channel.Title != want.Channel.Title || got.Channel.Link != want.Channel.Link {
+				t.Errorf("channel = %q %q, want %q %q", got.Channel.Title, got.Channel.Link, want.Channel.Title, want.Channel.Link)
+			}
+			if len(got.Channel.Item) != len(want.Channel.Item) {
+				t.Fatalf("got %d items, want %d", len(got.Channel.Item), len(want.Channel.Item))
+			}
+			for i, item := range got.Channel.Item {
+				wantItem := want.Channel.Item[i]
+				if item.Title != wantItem.Title || item.Link != wantItem.Link || item.Description != wantItem.Description {
+					t.Errorf("item %d = %q %q %q, want %q %q %q", i, item.Title, item.Link, item.Description, wantItem.Title, wantItem.Link, wantItem.Description)
+				}
+				if item.Creator != wantItem.Creator || !slices.Equal(item.Category, wantItem.Category) {
+					t.Errorf("item %d by %q in %q, want %q in %q", i, item.Creator, item.Category, wantItem.Creator, wantItem.Category)
+				}
+				if !slices.Equal(item.Enclosure, wantItem.Enclosure) {
+					t.Errorf("item %d enclosures = %+v, want %+v", i, item.Enclosure, wantItem.Enclosure)
+				}
+
+				published, err := time.Parse(time.RFC1123Z, item.PubDate)
+				if err != nil {
+					t.Errorf("item %d pubDate: %v", i, err)
+					continue
+				}
+				wantPublished, _ := time.Parse(time.RFC1123Z, wantItem.PubDate)
+				if !published.Equal(wantPublished) {
+					t.Errorf("item %d published %v, want %v", i, published, wantPublished)
+				}
+			}
+		})
+	}
+}
+
+func TestTimelineAtomParsesBack(t *testing.T) {
+	feed := testTimelineFeed()
+	const id = "urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	var buf bytes.Buffer
+	if err := encodeTimelineFeed(&buf, feed, timelineFormatAtom, id); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unmarshalling into atomFeed also checks the root element is in the Atom namespace
+	var doc atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("xml.Unmarshal: %v\n%s", err, buf.String())
+	}
+	if doc.ID != id || doc.Title != feed.Channel.Title {
+		t.Errorf("feed = %q %q, want %q %q", doc.ID, doc.Title, id, feed.Channel.Title)
+	}
+	if len(doc.Link) != 1 || doc.Link[0].Href != feed.Channel.Link {
+		t.Errorf("feed links = %+v, want %q", doc.Link, feed.Channel.Link)
+	}
+	// The feed was last updated when its newest entry was published
+	if want := "2024-03-01T10:30:00Z"; doc.Updated != want {
+		t.Errorf("feed updated %q, want %q", doc.Updated, want)
+	}
+
+	tests := []struct {
+		title     string
+		published time.Time
+		author    string
+		terms     []atomCategory
+		links     []atomLink
+		summary   string
+	}{
+		{
+			title:     "Say \"hello\" & <wave>",
+			published: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+			author:    "Alice",
+			terms:     []atomCategory{{Term: "Alice's blog"}, {Term: "greetings"}},
+			links: []atomLink{
+				{Href: "https://alice.example.com/hello?lang=en&ref=gator", Rel: "alternate"},
+				{Href: "https://alice.example.com/hello.mp3", Rel: "enclosure", Type: "audio/mpeg", Length: "1024"},
+			},
+			summary: "<p>Hello, <b>world</b> &amp; friends</p>",
+		},
+		{
+			title:     "Older news",
+			published: time.Date(2024, 2, 28, 7, 0, 0, 0, time.UTC),
+			terms:     []atomCategory{{Term: "Bob's news"}},
+			links:     []atomLink{{Href: "https://bob.example.com/news", Rel: "alternate"}},
+		},
+	}
+	if len(doc.Entry) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(doc.Entry), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			entry := doc.Entry[i]
+			if entry.Title != tt.title || entry.ID != tt.links[0].Href {
+				t.Errorf("entry = %q %q, want %q %q", entry.Title, entry.ID, tt.title, tt.links[0].Href)
+			}
+			if !slices.Equal(entry.Link, tt.links) {
+				t.Errorf("links = %+v, want %+v", entry.Link, tt.links)
+			}
+			if !slices.Equal(entry.Category, tt.terms) {
+				t.Errorf("categories = %+v, want %+v", entry.Category, tt.terms)
+			}
+			if entry.Summary.Type != "html" || entry.Summary.Body != tt.summary {
+				t.Errorf("summary = %+v, want html %q", entry.Summary, tt.summary)
+			}
+			if tt.author == "" && entry.Author != nil {
+				t.Errorf("author = %+v, want none", entry.Author)
+			}
+			if tt.author != "" && (entry.Author == nil || entry.Author.Name != tt.author) {
+				t.Errorf("author = %+v, want %q", entry.Author, tt.author)
+			}
+
+			for field, value := range map[string]string{"published": entry.Published, "updated": entry.Updated} {
+				date, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					t.Errorf("%s: %v", field, err)
+				} else if !date.Equal(tt.published) {
+					t.Errorf("%s %v, want %v", field, date, tt.published)
+				}
+			}
+		})
+	}
+}
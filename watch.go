@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// newPostsChannel is the channel ingestFeed notifies of every post it stores, with a
+// newPostEvent as JSON payload.
+const newPostsChannel = "gator_new_posts"
+
+const (
+	watchMinReconnect = 10 * time.Second
+	watchMaxReconnect = time.Minute
+	watchPingInterval = 90 * time.Second
+)
+
+type newPostEvent struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	PostID uuid.UUID `json:"post_id"`
+}
+
+func handlerWatch(s *state, cmd command) error {
+	fs := newFlagSet(cmd)
+	feedName := fs.String("feed", "", "only show posts of the named feed")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("command 'watch' expects flags only: [--feed <feed_name>]")
+	}
+
+	var feedID uuid.NullUUID
+	if *feedName != "" {
+		feed, err := s.db.GetFeed(context.Background(), *feedName)
+		if err != nil {
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	listener := pq.NewListener(s.cfg.DbURL, watchMinReconnect, watchMaxReconnect, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			fmt.Printf("Lost the connection to the database, reconnecting: %v\n", err)
+		case pq.ListenerEventReconnected:
+			fmt.Println("Reconnected, posts stored in the meantime weren't shown")
+		case pq.ListenerEventConnectionAttemptFailed:
+			fmt.Printf("Couldn't reconnect to the database: %v\n", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(newPostsChannel); err != nil {
+		return err
+	}
+
+	if *feedName != "" {
+		fmt.Printf("Watching for new posts of %s...\n", *feedName)
+	} else {
+		fmt.Println("Watching for new posts...")
+	}
+
+	ping := time.NewTicker(watchPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case notification := <-listener.Notify:
+			// A nil notification follows a reconnect
+			if notification == nil {
+				continue
+			}
+
+			event, ok, err := decodeNewPostEvent(notification.Extra, feedID)
+			if err != nil {
+				fmt.Printf("Ignoring malformed notification %q: %v\n", notification.Extra, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if err := printNewPost(s, event); err != nil {
+				return err
+			}
+		case <-ping.C:
+			// Pinging notices a dead connection even while no posts arrive
+			go listener.Ping()
+		}
+	}
+}
+
+// decodeNewPostEvent decodes the payload of a notification on newPostsChannel. It reports
+// false for posts of other feeds than feedID, if that is set.
+func decodeNewPostEvent(payload string, feedID uuid.NullUUID) (newPostEvent, bool, error) {
+	var event newPostEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return newPostEvent{}, false, err
+	}
+	if event.FeedID == uuid.Nil || event.PostID == uuid.Nil {
+		return newPostEvent{}, false, errors.New("feed_id and post_id are required")
+	}
+	if feedID.Valid && event.FeedID != feedID.UUID {
+		return event, false, nil
+	}
+	return event, true, nil
+}
+
+func printNewPost(s *state, event newPostEvent) error {
+	post, err := s.db.GetPost(context.Background(), event.PostID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted again before we got to it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	feed, err := s.db.GetFeedByID(context.Background(), event.FeedID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s [%s] %s (%s)\n", post.PublishedAt.Format("2006-01-02 15:04"), feed.Name, post.Title, shortID(post.ID))
+	fmt.Printf("    %s\n", post.Url)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeNewPostEvent(t *testing.T) {
+	feedID := uuid.MustParse("6f1c2a52-2d0e-4f4b-9a57-6a6f1f0d1a01")
+	otherFeedID := uuid.MustParse("0b7e6f1e-8c7d-4f0a-b0a4-3f2b5c9e2d02")
+	postID := uuid.MustParse("c3d9a1f4-5e6b-4c7d-8e9f-0a1b2c3d4e03")
+	payload := `{"feed_id": "` + feedID.String() + `", "post_id": "` + postID.String() + `"}`
+
+	tests := []struct {
+		name    string
+		payload string
+		feed    uuid.NullUUID
+		want    bool
+		wantErr bool
+	}{
+		{"any feed", payload, uuid.NullUUID{}, true, false},
+		{"watched feed", payload, uuid.NullUUID{UUID: feedID, Valid: true}, true, false},
+		{"other feed", payload, uuid.NullUUID{UUID: otherFeedID, Valid: true}, false, false},
+		{"not json", "post stored", uuid.NullUUID{}, false, true},
+		{"bad uuid", `{"feed_id": "nope", "post_id": "` + postID.String() + `"}`, uuid.NullUUID{}, false, true},
+		{"missing post", `{"feed_id": "` + feedID.String() + `"}`, uuid.NullUUID{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok, err := decodeNewPostEvent(tt.payload, tt.feed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.want {
+				t.Errorf("ok = %v, want %v", ok, tt.want)
+			}
+			if ok && (event.FeedID != feedID || event.PostID != postID) {
+				t.Errorf("event = %+v, want feed %s and post %s", event, feedID, postID)
+			}
+		})
+	}
+}
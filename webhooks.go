@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const webhookEventPostCreated = "post.created"
+
+const (
+	webhookStatusPending   = "pending"
+	webhookStatusDelivered = "delivered"
+	webhookStatusFailed    = "failed"
+)
+
+var webhookStatuses = []string{webhookStatusPending, webhookStatusDelivered, webhookStatusFailed}
+
+// A delivery is retried with exponential backoff, starting at webhookRetryDelay and capped
+// at webhookMaxRetryDelay, until it has been attempted webhookMaxAttempts times.
+const (
+	webhookMaxAttempts   = 10
+	webhookRetryDelay    = 30 * time.Second
+	webhookMaxRetryDelay = 6 * time.Hour
+)
+
+const (
+	webhookTimeout        = 10 * time.Second
+	webhookBatchSize      = 50
+	webhookWorkers        = 5
+	webhookDeliveryBudget = 30 * time.Second
+)
+
+// webhookPayload is the JSON body posted to a webhook. Its HMAC-SHA256, keyed with the
+// webhook's secret, is sent hex encoded in the X-Gator-Signature header as "sha256=<hex>".
+type webhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Post      webhookPost `json:"post"`
+}
+
+type webhookPost struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Feed        string    `json:"feed"`
+	FeedURL     string    `json:"feed_url"`
+	Author      string    `json:"author"`
+	Categories  []string  `json:"categories"`
+	PublishedAt time.Time `json:"published_at"`
+	Description string    `json:"description"`
+}
+
+// enqueueWebhooks queues a delivery of post to every webhook whose scope it is in. Posts a
+// webhook owner's filters hide aren't delivered to their webhooks.
+func enqueueWebhooks(s *state, hooks []database.Webhook, rules []compiledRule, feed database.Feed, post database.CreatePostRow, fp filteredPost) error {
+	for _, hook := range hooks {
+		if !webhookWants(hook, rules, fp) {
+			continue
+		}
+
+		now := time.Now()
+		id := uuid.New()
+		payload, err := json.Marshal(webhookPayload{
+			ID:        id.String(),
+			Event:     webhookEventPostCreated,
+			CreatedAt: now.UTC(),
+			Post: webhookPost{
+				ID:          post.ID.String(),
+				Title:       post.Title,
+				URL:         post.Url,
+				Feed:        post.FeedName,
+				FeedURL:     feed.Url,
+				Author:      post.Author,
+				Categories:  post.Categories,
+				PublishedAt: post.PublishedAt,
+				Description: post.Description,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = s.db.CreateWebhookDelivery(context.Background(), database.CreateWebhookDeliveryParams{
+			ID:            id,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+			WebhookID:     hook.ID,
+			PostID:        post.ID,
+			Payload:       string(payload),
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookWants reports whether post should be delivered to hook. The feed and tag scope of
+// the webhook are already checked when its feed's webhooks are loaded.
+func webhookWants(hook database.Webhook, rules []compiledRule, post filteredPost) bool {
+	matchedFilter := false
+	for _, rule := range rules {
+		if rule.UserID != hook.UserID || !rule.matches(post) {
+			continue
+		}
+		if rule.Action == filterActionHide {
+			return false
+		}
+		if hook.FilterID.Valid && rule.ID == hook.FilterID.UUID {
+			matchedFilter = true
+		}
+	}
+	return !hook.FilterID.Valid || matchedFilter
+}
+
+// deliverWebhooks attempts the deliveries that are due. Failed ones are rescheduled with
+// backoff, and given up on after webhookMaxAttempts attempts. Deliveries are posted by
+// webhookWorkers at once and the whole batch gets webhookDeliveryBudget, so slow
+// endpoints can't hold up the agg loop; deliveries not attempted in time stay due.
+func deliverWebhooks(s *state, now time.Time) error {
+	deliveries, err := s.db.GetDueWebhookDeliveries(context.Background(), database.GetDueWebhookDeliveriesParams{
+		Now:           now,
+		DeliveryLimit: webhookBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookDeliveryBudget)
+	defer cancel()
+
+	queue := make(chan database.GetDueWebhookDeliveriesRow)
+	errs := make(chan error, len(deliveries))
+	var wg sync.WaitGroup
+	for range min(webhookWorkers, len(deliveries)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range queue {
+				errs <- deliverWebhook(ctx, s, now, delivery)
+			}
+		}()
+	}
+	for _, delivery := range deliveries {
+		queue <- delivery
+	}
+	close(queue)
+	wg.Wait()
+	close(errs)
+
+	var deliveryErrs []error
+	for err := range errs {
+		deliveryErrs = append(deliveryErrs, err)
+	}
+	return errors.Join(deliveryErrs...)
+}
+
+// deliverWebhook posts a single delivery and records the outcome. It leaves the delivery
+// untouched once ctx is done.
+func deliverWebhook(ctx context.Context, s *state, now time.Time, delivery database.GetDueWebhookDeliveriesRow) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	statusCode, err := postWebhook(ctx, delivery.Url, delivery.Secret, delivery.ID, []byte(delivery.Payload))
+	if err == nil {
+		return s.db.MarkWebhookDeliveryDelivered(context.Background(), database.MarkWebhookDeliveryDeliveredParams{
+			ID:             delivery.ID,
+			LastStatusCode: int32(statusCode),
+			DeliveredAt:    sql.NullTime{Time: time.Now(), Valid: true},
+		})
+	}
+
+	attempts := int(delivery.Attempts) + 1
+	status := webhookStatusPending
+	if attempts >= webhookMaxAttempts {
+		status = webhookStatusFailed
+	}
+	fmt.Printf("webhook delivery %s to %q failed (attempt %d): %v\n", shortID(delivery.ID), delivery.Url, attempts, err)
+
+	return s.db.MarkWebhookDeliveryFailed(context.Background(), database.MarkWebhookDeliveryFailedParams{
+		Status:         status,
+		NextAttemptAt:  now.Add(webhookBackoff(attempts)),
+		LastStatusCode: int32(statusCode),
+		LastError:      err.Error(),
+		ID:             delivery.ID,
+	})
+}
+
+// webhookBackoff is how long to wait before retrying a delivery attempted attempts times.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxRetryDelay {
+			return webhookMaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// postWebhook posts payload to hookURL and returns the response's status code. Any status
+// other than 2xx is an error.
+func postWebhook(ctx context.Context, hookURL, secret string, deliveryID uuid.UUID, payload []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", hookURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gator-Event", webhookEventPostCreated)
+	req.Header.Set("X-Gator-Delivery", deliveryID.String())
+	req.Header.Set("X-Gator-Signature", "sha256="+signPayload(secret, payload))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func handlerWebhooks(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return errors.New("command 'webhooks' expects a subcommand: add | list | rm | deliveries | retry")
+	}
+
+	sub := command{name: "webhooks " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "add":
+		return handlerWebhooksAdd(s, sub, user)
+	case "list":
+		return handlerWebhooksList(s, sub, user)
+	case "rm":
+		return handlerWebhooksRemove(s, sub, user)
+	case "deliveries":
+		return handlerWebhooksDeliveries(s, sub, user)
+	case "retry":
+		return handlerWebhooksRetry(s, sub, user)
+	default:
+		return fmt.Errorf("unknown 'webhooks' subcommand %q, expected add | list | rm | deliveries | retry", cmd.args[0])
+	}
+}
+
+func handlerWebhooksAdd(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	feedName := fs.String("feed", "", "only deliver posts of the named feed")
+	tag := fs.String("tag", "", "only deliver posts of feeds with this tag")
+	filter := fs.String("filter", "", "only deliver posts matching this filter")
+	secret := fs.String("secret", "", "secret the payloads are signed with (generated when empty)")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("command 'webhooks add' expects one argument: <url> [--feed <name>] [--tag <tag>] [--filter <filter_id>] [--secret <secret>]")
+	}
+
+	hookURL, err := url.Parse(args[0])
+	if err != nil || (hookURL.Scheme != "http" && hookURL.Scheme != "https") || hookURL.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q, expected an http or https URL", args[0])
+	}
+
+	// Only posts of followed feeds are delivered, so a webhook for any other feed would never fire
+	var feedID uuid.NullUUID
+	if *feedName != "" {
+		follow, err := followedFeed(s, user, *feedName)
+		if err != nil {
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: follow.FeedID, Valid: true}
+	}
+
+	var filterID uuid.NullUUID
+	if *filter != "" {
+		rule, err := findFilterRule(s, user, *filter)
+		if err != nil {
+			return err
+		}
+		if rule.Action == filterActionHide {
+			return fmt.Errorf("filter %s hides the posts it matches, which are never delivered", shortID(rule.ID))
+		}
+		filterID = uuid.NullUUID{UUID: rule.ID, Valid: true}
+	}
+
+	if *secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		*secret = hex.EncodeToString(b)
+	}
+
+	hook, err := s.db.CreateWebhook(context.Background(), database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Url:       hookURL.String(),
+		Secret:    *secret,
+		FeedID:    feedID,
+		Tag:       *tag,
+		FilterID:  filterID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Added webhook %s\n", shortID(hook.ID))
+	fmt.Printf("Secret: %s\n", hook.Secret)
+	return nil
+}
+
+func handlerWebhooksList(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("command 'webhooks list' doesn't expect arguments")
+	}
+
+	hooks, err := s.db.GetWebhooksForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		var scope []string
+		if hook.FeedName.Valid {
+			scope = append(scope, "feed "+hook.FeedName.String)
+		}
+		if hook.Tag != "" {
+			scope = append(scope, "tag "+hook.Tag)
+		}
+		if hook.FilterID.Valid {
+			scope = append(scope, "filter "+shortID(hook.FilterID.UUID))
+		}
+		if len(scope) == 0 {
+			scope = append(scope, "all feeds")
+		}
+		fmt.Printf("* %s - %s (%s), %d pending, %d failed\n", shortID(hook.ID), hook.Url, strings.Join(scope, ", "), hook.Pending, hook.Failed)
+	}
+
+	return nil
+}
+
+func handlerWebhooksRemove(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'webhooks rm' expects only one argument: <webhook id>")
+	}
+
+	hook, err := findWebhook(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.DeleteWebhook(context.Background(), database.DeleteWebhookParams{
+		ID:     hook.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed webhook %s\n", shortID(hook.ID))
+	return nil
+}
+
+func handlerWebhooksDeliveries(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	status := fs.String("status", "", "only show deliveries with this status: "+strings.Join(webhookStatuses, "|"))
+	limit := fs.Int("limit", 20, "number of latest deliveries to show")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return errors.New("command 'webhooks deliveries' expects at most one argument: [<webhook id>] [--status <status>] [--limit <n>]")
+	}
+	if *status != "" && !slices.Contains(webhookStatuses, *status) {
+		return fmt.Errorf("invalid status %q, expected one of %s", *status, strings.Join(webhookStatuses, ", "))
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("invalid limit value: %d", *limit)
+	}
+
+	var webhookID uuid.NullUUID
+	if len(args) == 1 {
+		hook, err := findWebhook(s, user, args[0])
+		if err != nil {
+			return err
+		}
+		webhookID = uuid.NullUUID{UUID: hook.ID, Valid: true}
+	}
+
+	deliveries, err := s.db.GetWebhookDeliveries(context.Background(), database.GetWebhookDeliveriesParams{
+		UserID:        user.ID,
+		WebhookID:     webhookID,
+		Status:        sql.NullString{String: *status, Valid: *status != ""},
+		DeliveryLimit: int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		fmt.Printf("* %s - %s %q -> %s\n", shortID(delivery.ID), delivery.CreatedAt.Format("2006-01-02 15:04"), delivery.PostTitle, delivery.WebhookUrl)
+		switch delivery.Status {
+		case webhookStatusDelivered:
+			fmt.Printf("    delivered at %s (HTTP %d)\n", delivery.DeliveredAt.Time.Format("2006-01-02 15:04"), delivery.LastStatusCode)
+		case webhookStatusPending:
+			fmt.Printf("    pending, next attempt at %s", delivery.NextAttemptAt.Format("2006-01-02 15:04"))
+			if delivery.Attempts > 0 {
+				fmt.Printf(" after %d attempts, last error: %s", delivery.Attempts, delivery.LastError)
+			}
+			fmt.Println()
+		case webhookStatusFailed:
+			fmt.Printf("    failed after %d attempts, last error: %s\n", delivery.Attempts, delivery.LastError)
+		}
+	}
+
+	return nil
+}
+
+func handlerWebhooksRetry(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("command 'webhooks retry' expects only one argument: <webhook id>")
+	}
+
+	hook, err := findWebhook(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	n, err := s.db.RetryWebhookDeliveries(context.Background(), database.RetryWebhookDeliveriesParams{
+		WebhookID:     hook.ID,
+		NextAttemptAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Queued %d failed deliveries of webhook %s again\n", n, shortID(hook.ID))
+	return nil
+}
+
+// findWebhook returns the user's webhook whose ID starts with ref.
+func findWebhook(s *state, user database.User, ref string) (database.GetWebhooksForUserRow, error) {
+	if len(ref) < minIDPrefixLength {
+		return database.GetWebhooksForUserRow{}, fmt.Errorf("webhook ID %q is too short, type at least %d characters of it", ref, minIDPrefixLength)
+	}
+
+	hooks, err := s.db.GetWebhooksForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.GetWebhooksForUserRow{}, err
+	}
+
+	var found []database.GetWebhooksForUserRow
+	for _, hook := range hooks {
+		if strings.HasPrefix(hook.ID.String(), strings.ToLower(ref)) {
+			found = append(found, hook)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return database.GetWebhooksForUserRow{}, fmt.Errorf("webhook %q not found", ref)
+	case 1:
+		return found[0], nil
+	default:
+		return database.GetWebhooksForUserRow{}, fmt.Errorf("webhook %q is ambiguous, type more characters of its ID", ref)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GLobyNew/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPostWebhookSignsPayload(t *testing.T) {
+	const secret = "s3cret"
+	payload := []byte(`{"event":"post.created"}`)
+	deliveryID := uuid.New()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := r.Header.Get("X-Gator-Signature"); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if got := r.Header.Get("X-Gator-Delivery"); got != deliveryID.String() {
+			t.Errorf("delivery = %q, want %q", got, deliveryID)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("content type = %q", got)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	status, err := postWebhook(context.Background(), srv.URL, secret, deliveryID, payload)
+	if err != nil {
+		t.Fatalf("postWebhook: %v", err)
+	}
+	if status != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", status, http.StatusAccepted)
+	}
+}
+
+func TestPostWebhookFailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	status, err := postWebhook(context.Background(), srv.URL, "secret", uuid.New(), []byte(`{}`))
+	if err == nil {
+		t.Fatal("postWebhook succeeded, want an error")
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{9, 128 * time.Minute},
+		{11, webhookMaxRetryDelay},
+		{50, webhookMaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookWants(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	goRule := database.FilterRule{ID: uuid.New(), UserID: owner, Field: "title", MatchType: "keyword", Pattern: "golang", Action: filterActionTag, Tag: "go"}
+	hideRule := database.FilterRule{ID: uuid.New(), UserID: owner, Field: "title", MatchType: "keyword", Pattern: "sponsored", Action: filterActionHide}
+	otherHide := database.FilterRule{ID: uuid.New(), UserID: other, Field: "title", MatchType: "keyword", Pattern: "golang", Action: filterActionHide}
+	rules := compileRules([]database.FilterRule{goRule, hideRule, otherHide})
+
+	everything := database.Webhook{UserID: owner}
+	filtered := database.Webhook{UserID: owner, FilterID: uuid.NullUUID{UUID: goRule.ID, Valid: true}}
+
+	tests := []struct {
+		name  string
+		hook  database.Webhook
+		title string
+		want  bool
+	}{
+		{"unfiltered", everything, "Release notes", true},
+		{"hidden by the owner", everything, "Sponsored: golang jobs", false},
+		{"hidden by someone else", everything, "Golang 2.0", true},
+		{"matches the filter", filtered, "Golang 2.0", true},
+		{"misses the filter", filtered, "Rust 2.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhookWants(tt.hook, rules, filteredPost{Title: tt.title}); got != tt.want {
+				t.Errorf("webhookWants = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}